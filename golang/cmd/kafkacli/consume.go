@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// consoleGroup - группа консьюмера без -group; консьюмер не подписывается на топик
+// и не фиксирует смещения, поэтому не влияет ни на какие группы
+const consoleGroup = "kafkacli"
+
+// runConsume выводит сообщения топика в stdout
+func runConsume(g *globals, args []string) error {
+	flags := flag.NewFlagSet("consume", flag.ExitOnError)
+	topic := flags.String("topic", "", "топик (обязательно)")
+	partition := flags.Int("partition", -1, "партиция; по умолчанию все")
+	offset := flags.String("offset", "beginning", "начало чтения: beginning, end или номер смещения")
+	from := flags.String("from-time", "", "читать с первого сообщения не раньше времени RFC3339")
+	group := flags.String("group", "", "группа консьюмеров; с ней консьюмер подписывается на топик и фиксирует смещения")
+	count := flags.Int("n", 0, "завершиться после n сообщений; 0 - без ограничения")
+	format := flags.String("format", "value", "формат вывода: value, kv или json")
+	keySeparator := flags.String("key-separator", "\t", "разделитель ключа и значения для формата kv")
+	flags.Parse(args)
+
+	if *topic == "" {
+		return errors.New("usage: kafkacli consume -topic t [-partition p] [-offset o|-from-time t] [-group g] [-n count] [-format value|kv|json]")
+	}
+	registry, err := g.registryClient()
+	if err != nil {
+		return err
+	}
+	write, err := messageWriter(*format, *keySeparator, newPayloadDecoder(registry, g.logger()))
+	if err != nil {
+		return err
+	}
+
+	config := g.clientConfig()
+	var opts []kafkalib.Option
+	if *group != "" {
+		// Подписка: начальная позиция задается auto.offset.reset, если у группы нет смещений
+		if *from != "" || *partition >= 0 {
+			return errors.New("-from-time and -partition cannot be used with -group")
+		}
+		switch *offset {
+		case "beginning":
+			config["auto.offset.reset"] = "earliest"
+		case "end":
+			config["auto.offset.reset"] = "latest"
+		default:
+			return errors.New("-offset must be beginning or end with -group")
+		}
+		config["group.id"] = *group
+	} else {
+		config["group.id"] = consoleGroup
+		opts = append(opts, kafkalib.WithoutOffsetCommit())
+		partitions, err := consumePartitions(g, *topic, *partition, *offset)
+		if err != nil {
+			return err
+		}
+		if *from != "" {
+			t, err := time.Parse(time.RFC3339, *from)
+			if err != nil {
+				return fmt.Errorf("invalid -from-time: %w", err)
+			}
+			opts = append(opts, kafkalib.WithAssignmentAtTime(partitions, t))
+		} else {
+			opts = append(opts, kafkalib.WithAssignment(partitions))
+		}
+	}
+
+	consumer, err := kafkalib.NewConsumer([]string{*topic}, config, g.logger(), opts...)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	received := 0
+	var writeErr error
+	err = consumer.Run(ctx, func(ctx context.Context, msg *kafka.Message) error {
+		// Вывод закрыт (например, head завершился): повторять чтение бессмысленно
+		if err := write(msg); err != nil {
+			writeErr = err
+			return kafkalib.ErrStopConsuming
+		}
+		received++
+		if *count > 0 && received >= *count {
+			return kafkalib.ErrStopConsuming
+		}
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	fmt.Fprintf(os.Stderr, "%d messages received\n", received)
+	return errors.Join(writeErr, err)
+}
+
+// consumePartitions возвращает партиции топика для ручного назначения с начальным смещением
+func consumePartitions(g *globals, topic string, partition int, offset string) ([]kafka.TopicPartition, error) {
+	var start kafka.Offset
+	switch offset {
+	case "beginning":
+		start = kafka.OffsetBeginning
+	case "end":
+		start = kafka.OffsetEnd
+	default:
+		n, err := strconv.ParseInt(offset, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid -offset %q", offset)
+		}
+		start = kafka.Offset(n)
+	}
+
+	if partition >= 0 {
+		return []kafka.TopicPartition{{Topic: &topic, Partition: int32(partition), Offset: start}}, nil
+	}
+
+	client, err := g.adminClient()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	descriptions, err := client.DescribeTopics(context.Background(), []string{topic})
+	if err != nil {
+		return nil, err
+	}
+	partitions := make([]kafka.TopicPartition, descriptions[0].Partitions)
+	for i := range partitions {
+		partitions[i] = kafka.TopicPartition{Topic: &topic, Partition: int32(i), Offset: start}
+	}
+	return partitions, nil
+}
+
+// jsonMessage - сообщение в формате вывода json
+type jsonMessage struct {
+	Topic         string            `json:"topic"`
+	Partition     int32             `json:"partition"`
+	Offset        int64             `json:"offset"`
+	Timestamp     time.Time         `json:"timestamp"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Key           json.RawMessage   `json:"key"`
+	KeySchemaID   int               `json:"key_schema_id,omitempty"`
+	KeyHex        bool              `json:"key_hex,omitempty"`
+	Value         json.RawMessage   `json:"value"`
+	ValueSchemaID int               `json:"value_schema_id,omitempty"`
+	ValueHex      bool              `json:"value_hex,omitempty"`
+}
+
+// messageWriter возвращает функцию вывода сообщений в указанном формате
+func messageWriter(format string, keySeparator string, decoder *payloadDecoder) (func(*kafka.Message) error, error) {
+	switch format {
+	case "value":
+		return func(msg *kafka.Message) error {
+			_, err := fmt.Println(decoder.decode(msg.Value).Text)
+			return err
+		}, nil
+	case "kv":
+		return func(msg *kafka.Message) error {
+			_, err := fmt.Printf("%s%s%s\n", decoder.decode(msg.Key).Text, keySeparator, decoder.decode(msg.Value).Text)
+			return err
+		}, nil
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		return func(msg *kafka.Message) error {
+			key, value := decoder.decode(msg.Key), decoder.decode(msg.Value)
+			m := jsonMessage{
+				Topic:         *msg.TopicPartition.Topic,
+				Partition:     msg.TopicPartition.Partition,
+				Offset:        int64(msg.TopicPartition.Offset),
+				Timestamp:     msg.Timestamp,
+				Key:           key.JSON,
+				KeySchemaID:   key.SchemaID,
+				KeyHex:        key.Hex,
+				Value:         value.JSON,
+				ValueSchemaID: value.SchemaID,
+				ValueHex:      value.Hex,
+			}
+			if len(msg.Headers) > 0 {
+				m.Headers = make(map[string]string, len(msg.Headers))
+				for _, h := range msg.Headers {
+					m.Headers[h.Key] = decodeText(h.Value).Text
+				}
+			}
+			return encoder.Encode(m)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"unicode/utf8"
+
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+	"github.com/riferrei/srclient"
+)
+
+// payload - ключ или значение сообщения, подготовленные для вывода
+type payload struct {
+	// JSON - представление для формата json
+	JSON json.RawMessage
+	// Text - представление для форматов value и kv
+	Text string
+	// SchemaID - идентификатор схемы для данных в формате Confluent, иначе 0
+	SchemaID int
+	// Hex - данные не являются текстом и выведены в шестнадцатеричном виде
+	Hex bool
+}
+
+// payloadDecoder определяет формат ключей и значений: Avro в формате Confluent
+// декодируется по схеме из Schema Registry, JSON и текст выводятся как есть,
+// остальные данные - в шестнадцатеричном виде
+type payloadDecoder struct {
+	// avro равен nil, если Schema Registry не задан
+	avro   *kafkalib.AvroDecoder
+	logger *slog.Logger
+	// failed - схемы, ошибка декодирования которых уже выведена
+	failed map[int]bool
+}
+
+// newPayloadDecoder создает декодер; registry может быть nil
+func newPayloadDecoder(registry srclient.ISchemaRegistryClient, logger *slog.Logger) *payloadDecoder {
+	d := &payloadDecoder{logger: logger, failed: make(map[int]bool)}
+	if registry != nil {
+		d.avro = kafkalib.NewAvroDecoder(registry)
+	}
+	return d
+}
+
+// decode подготавливает данные для вывода
+func (d *payloadDecoder) decode(data []byte) payload {
+	if data == nil {
+		return payload{JSON: json.RawMessage("null")}
+	}
+
+	// Данные в формате Confluent: Avro декодируется по схеме, JSON Schema выводится без заголовка
+	if schemaID, body, err := kafkalib.ParseSchemaID(data); err == nil {
+		if d.avro != nil {
+			textual, _, err := d.avro.DecodeJSON(data)
+			if err == nil {
+				return payload{JSON: textual, Text: string(textual), SchemaID: schemaID}
+			}
+			if !d.failed[schemaID] {
+				d.failed[schemaID] = true
+				d.logger.Warn("failed to decode avro payload", slog.Int("schema_id", schemaID), slog.Any(kafkalib.LogKeyError, err))
+			}
+		}
+		if isJSONDocument(body) {
+			return payload{JSON: body, Text: string(body), SchemaID: schemaID}
+		}
+	}
+
+	return decodeText(data)
+}
+
+// decodeText выводит текст как есть, а остальные данные - в шестнадцатеричном виде
+func decodeText(data []byte) payload {
+	if utf8.Valid(data) {
+		if isJSONDocument(data) {
+			return payload{JSON: data, Text: string(data)}
+		}
+		text, _ := json.Marshal(string(data))
+		return payload{JSON: text, Text: string(data)}
+	}
+
+	encoded := hex.EncodeToString(data)
+	text, _ := json.Marshal(encoded)
+	return payload{JSON: text, Text: encoded, Hex: true}
+}
+
+// isJSONDocument сообщает, что data - JSON-объект или массив;
+// строки и числа выводятся как текст, чтобы тип ключа не зависел от его содержимого
+func isJSONDocument(data []byte) bool {
+	for _, b := range data {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return json.Valid(data)
+		}
+		return false
+	}
+	return false
+}
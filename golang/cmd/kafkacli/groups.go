@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kafka-examples/golang/src/admin"
+)
+
+// groupsUsage - справка по подкоманде groups
+const groupsUsage = `usage: kafkacli groups list
+       kafkacli groups describe <group>
+       kafkacli groups lag <group>
+       kafkacli groups reset <group> -topic t [-topic t2] -to-earliest|-to-latest|-to-datetime T|-to-offset N|-shift-by N [-execute]`
+
+// runGroups выполняет операции с группами консьюмеров
+func runGroups(g *globals, args []string) error {
+	if len(args) == 0 {
+		return errors.New(groupsUsage)
+	}
+	action, args := args[0], args[1:]
+	if action != "list" && (len(args) == 0 || strings.HasPrefix(args[0], "-")) {
+		return errors.New(groupsUsage)
+	}
+
+	client, err := g.adminClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	switch action {
+	case "list":
+		return listGroups(ctx, client)
+	case "describe":
+		return describeGroup(ctx, client, args[0])
+	case "lag":
+		return groupLag(ctx, client, args[0])
+	case "reset":
+		return resetOffsets(ctx, client, args[0], args[1:])
+	default:
+		return errors.New(groupsUsage)
+	}
+}
+
+// listGroups выводит группы кластера
+func listGroups(ctx context.Context, client *admin.Client) error {
+	groups, err := client.ListGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tSTATE\tSIMPLE")
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s\t%s\t%t\n", group.GroupID, group.State, group.Simple)
+	}
+	return w.Flush()
+}
+
+// describeGroup выводит участников группы и назначенные им партиции
+func describeGroup(ctx context.Context, client *admin.Client, group string) error {
+	description, err := client.DescribeGroup(ctx, group)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Group %s: state %s, assignor %s, %d members\n\n",
+		description.GroupID, description.State, description.PartitionAssignor, len(description.Members))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONSUMER-ID\tINSTANCE-ID\tCLIENT-ID\tHOST\tPARTITIONS")
+	for _, m := range description.Members {
+		partitions := make([]string, 0, len(m.Partitions))
+		for _, tp := range m.Partitions {
+			partitions = append(partitions, fmt.Sprintf("%s[%d]", *tp.Topic, tp.Partition))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			m.ConsumerID, dash(m.GroupInstanceID), m.ClientID, m.Host, strings.Join(partitions, ","))
+	}
+	return w.Flush()
+}
+
+// groupLag выводит отставание группы по партициям
+func groupLag(ctx context.Context, client *admin.Client, group string) error {
+	lags, err := client.GroupLag(ctx, group)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tPARTITION\tCOMMITTED\tEND\tLAG\tCONSUMER-ID")
+	for _, l := range lags {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\n",
+			l.Topic, l.Partition, formatOffset(l.Committed), l.End, l.Lag, dash(l.ConsumerID))
+		total += l.Lag
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nTotal lag: %d\n", total)
+	return nil
+}
+
+// topicsFlag собирает повторяющиеся параметры -topic
+type topicsFlag []string
+
+func (t *topicsFlag) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *topicsFlag) Set(value string) error {
+	*t = append(*t, strings.Split(value, ",")...)
+	return nil
+}
+
+// resetOffsets сбрасывает смещения группы; без -execute только показывает новые смещения
+func resetOffsets(ctx context.Context, client *admin.Client, group string, args []string) error {
+	var topics topicsFlag
+	flags := flag.NewFlagSet("groups reset", flag.ExitOnError)
+	flags.Var(&topics, "topic", "топик; можно указать несколько раз или через запятую")
+	toEarliest := flags.Bool("to-earliest", false, "в начало партиций")
+	toLatest := flags.Bool("to-latest", false, "в конец партиций")
+	toDatetime := flags.String("to-datetime", "", "к первому сообщению не раньше времени RFC3339")
+	toOffset := flags.Int64("to-offset", -1, "к указанному смещению")
+	shiftBy := flags.Int64("shift-by", 0, "сдвинуть текущее смещение на N (может быть отрицательным)")
+	execute := flags.Bool("execute", false, "применить изменения; без флага только показать план")
+	flags.Parse(args)
+
+	if len(topics) == 0 {
+		return fmt.Errorf("at least one -topic is required")
+	}
+
+	var specs []admin.ResetSpec
+	if *toEarliest {
+		specs = append(specs, admin.ResetSpec{Mode: admin.ResetEarliest})
+	}
+	if *toLatest {
+		specs = append(specs, admin.ResetSpec{Mode: admin.ResetLatest})
+	}
+	if *toDatetime != "" {
+		t, err := time.Parse(time.RFC3339, *toDatetime)
+		if err != nil {
+			return fmt.Errorf("invalid -to-datetime: %w", err)
+		}
+		specs = append(specs, admin.ResetSpec{Mode: admin.ResetTimestamp, Timestamp: t})
+	}
+	if *toOffset >= 0 {
+		specs = append(specs, admin.ResetSpec{Mode: admin.ResetOffset, Offset: *toOffset})
+	}
+	if *shiftBy != 0 {
+		specs = append(specs, admin.ResetSpec{Mode: admin.ResetShift, Shift: *shiftBy})
+	}
+	if len(specs) != 1 {
+		return fmt.Errorf("exactly one of -to-earliest, -to-latest, -to-datetime, -to-offset, -shift-by is required")
+	}
+
+	resets, err := client.ResetOffsets(ctx, group, topics, specs[0], !*execute)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tPARTITION\tCURRENT\tNEW")
+	for _, r := range resets {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\n", r.Topic, r.Partition, formatOffset(r.Current), r.Target)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if *execute {
+		fmt.Println("\nOffsets reset.")
+	} else {
+		fmt.Println("\nDry run. Use -execute to apply.")
+	}
+	return nil
+}
+
+// formatOffset выводит смещение или "-", если его нет
+func formatOffset(offset int64) string {
+	if offset < 0 {
+		return "-"
+	}
+	return fmt.Sprint(offset)
+}
+
+// dash возвращает s или "-", если s пусто
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
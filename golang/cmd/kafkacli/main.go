@@ -0,0 +1,138 @@
+// Команда kafkacli - утилита для работы с кластером Kafka на основе пакетов src/kafka и src/admin
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kafka-examples/golang/src/admin"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+	"github.com/riferrei/srclient"
+)
+
+// command - подкоманда утилиты
+type command struct {
+	usage string
+	run   func(g *globals, args []string) error
+}
+
+// commands - доступные подкоманды
+var commands = map[string]command{
+	"consume":  {"-topic t [-offset o|-from-time t] [-n count] [-format value|kv|json]  вывести сообщения топика", runConsume},
+	"dump":     {"-topic t [-f file] [-format json|binary] [-partition p] [-from-offset n] [-to-time t]  выгрузить сообщения в файл", runDump},
+	"groups":   {"list|describe|lag|reset <group>  группы консьюмеров: участники, отставание, сброс смещений", runGroups},
+	"produce":  {"-topic t [-f file] [-key-separator sep] [-H key=value]  отправить строки stdin или файла", runProduce},
+	"restore":  {"-topic t [-f file] [-partition keep|any|n] [-rekey header:name|field:name]  загрузить сообщения из файла", runRestore},
+	"topics":   {"list|describe|create|alter|delete  управление топиками", runTopics},
+	"topology": {"plan|apply -f topology.yaml  сравнить кластер с файлом топологии и применить изменения", runTopology},
+}
+
+// globals - общие параметры подключения
+type globals struct {
+	brokers        string
+	schemaRegistry string
+	config         configFlag
+	verbose        bool
+}
+
+// configFlag собирает повторяющиеся параметры -X key=value
+type configFlag map[string]string
+
+func (c configFlag) String() string {
+	return fmt.Sprint(map[string]string(c))
+}
+
+func (c configFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	c[key] = val
+	return nil
+}
+
+func main() {
+	g := &globals{config: configFlag{}}
+
+	flags := flag.NewFlagSet("kafkacli", flag.ExitOnError)
+	flags.StringVar(&g.brokers, "brokers", "kafka:29092", "адреса брокеров (bootstrap.servers)")
+	flags.StringVar(&g.schemaRegistry, "schema-registry", "", "адрес Schema Registry")
+	flags.Var(g.config, "X", "параметр librdkafka key=value; можно указать несколько раз")
+	flags.BoolVar(&g.verbose, "v", false, "подробные логи библиотеки")
+	flags.Usage = func() { usage(flags) }
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		usage(flags)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flags.Arg(0))
+		usage(flags)
+		os.Exit(2)
+	}
+
+	if err := cmd.run(g, flags.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// usage выводит справку по утилите
+func usage(flags *flag.FlagSet) {
+	fmt.Fprintln(os.Stderr, "Usage: kafkacli [flags] <command> [args]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, commands[name].usage)
+	}
+
+	fmt.Fprintln(os.Stderr, "\nFlags:")
+	flags.PrintDefaults()
+}
+
+// logger возвращает логгер библиотеки; без -v выводятся только предупреждения и ошибки
+func (g *globals) logger() *slog.Logger {
+	level := slog.LevelWarn
+	if g.verbose {
+		level = slog.LevelInfo
+	}
+	return kafkalib.NewLogger(os.Stderr, level, false)
+}
+
+// clientConfig возвращает конфигурацию клиентов Kafka
+func (g *globals) clientConfig() map[string]string {
+	config := map[string]string{"bootstrap.servers": g.brokers}
+	for k, v := range g.config {
+		config[k] = v
+	}
+	return config
+}
+
+// adminClient создает административный клиент
+func (g *globals) adminClient() (*admin.Client, error) {
+	return admin.NewClient(g.clientConfig(), g.logger())
+}
+
+// registryClient создает клиент Schema Registry или возвращает nil, если адрес не задан
+func (g *globals) registryClient() (srclient.ISchemaRegistryClient, error) {
+	if g.schemaRegistry == "" {
+		return nil, nil
+	}
+	client, err := kafkalib.NewSchemaRegistryClient(kafkalib.SchemaRegistryConfig{URL: g.schemaRegistry})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+const (
+	// maxLineSize - максимальный размер строки входных данных
+	maxLineSize = 10 * 1024 * 1024
+	// maxInFlight - максимальное число отправленных, но не доставленных сообщений
+	maxInFlight = 10000
+)
+
+// headersFlag собирает повторяющиеся параметры -H key=value
+type headersFlag []kafka.Header
+
+func (h *headersFlag) String() string {
+	pairs := make([]string, len(*h))
+	for i, header := range *h {
+		pairs[i] = header.Key + "=" + string(header.Value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h *headersFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	*h = append(*h, kafka.Header{Key: key, Value: []byte(val)})
+	return nil
+}
+
+// runProduce отправляет в топик по сообщению на каждую строку stdin или файла
+func runProduce(g *globals, args []string) error {
+	var headers headersFlag
+	flags := flag.NewFlagSet("produce", flag.ExitOnError)
+	topic := flags.String("topic", "", "топик (обязательно)")
+	file := flags.String("f", "", "файл с сообщениями; по умолчанию stdin")
+	keySeparator := flags.String("key-separator", "", "разделитель ключа и значения в строке; по умолчанию сообщения без ключа")
+	flags.Var(&headers, "H", "заголовок key=value для всех сообщений; можно указать несколько раз")
+	flags.Parse(args)
+
+	if *topic == "" {
+		return errors.New("usage: kafkacli produce -topic t [-f file] [-key-separator sep] [-H key=value]")
+	}
+
+	input := io.Reader(os.Stdin)
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", *file, err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	producer, err := kafkalib.NewProducer(*topic, g.clientConfig(), g.logger())
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	producer.ProcessDeliveryReports(ctx)
+
+	deliveries := make(chan kafka.Event, maxInFlight)
+	inFlight, delivered := 0, 0
+	var errs []error
+
+	// await дожидается одного отчета о доставке
+	await := func() error {
+		select {
+		case ev := <-deliveries:
+			inFlight--
+			if msg, ok := ev.(*kafka.Message); ok {
+				if msg.TopicPartition.Error != nil {
+					errs = append(errs, msg.TopicPartition.Error)
+				} else {
+					delivered++
+				}
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// send отправляет сообщение; при заполненной очереди librdkafka дожидается доставки
+	// ранее отправленных сообщений и повторяет отправку
+	send := func(message *kafka.Message) error {
+		for {
+			if inFlight == maxInFlight {
+				if err := await(); err != nil {
+					return err
+				}
+			}
+			err := producer.SendMessage(ctx, message, deliveries)
+			if err == nil {
+				inFlight++
+				return nil
+			}
+			var kafkaErr kafka.Error
+			if !errors.As(err, &kafkaErr) || kafkaErr.Code() != kafka.ErrQueueFull || inFlight == 0 {
+				return err
+			}
+			if err := await(); err != nil {
+				return err
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		message := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Partition: kafka.PartitionAny},
+			Value:          []byte(line),
+			Headers:        append([]kafka.Header(nil), headers...),
+		}
+		if *keySeparator != "" {
+			key, value, ok := strings.Cut(line, *keySeparator)
+			if !ok {
+				return fmt.Errorf("line %d: key separator %q not found", lineNumber, *keySeparator)
+			}
+			message.Key, message.Value = []byte(key), []byte(value)
+		}
+
+		if err := send(message); err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	// Дожидаемся отчетов о доставке всех отправленных сообщений
+	for inFlight > 0 {
+		if err := await(); err != nil {
+			return fmt.Errorf("%d of %d messages not confirmed: %w", inFlight, delivered+len(errs)+inFlight, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d messages sent to %s\n", delivered, *topic)
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to deliver %d messages: %w", len(errs), err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kafka-examples/golang/src/admin"
+)
+
+// topicsUsage - справка по подкоманде topics
+const topicsUsage = `usage: kafkacli topics list
+       kafkacli topics describe <topic> [topic...]
+       kafkacli topics create <topic> [-partitions n] [-replication-factor n] [-retention d] [-cleanup-policy p] [-config key=value]
+       kafkacli topics alter <topic> [-partitions n] [-config key=value]
+       kafkacli topics delete <topic> [-auto-approve]`
+
+// runTopics выполняет операции с топиками
+func runTopics(g *globals, args []string) error {
+	if len(args) == 0 {
+		return errors.New(topicsUsage)
+	}
+	action, args := args[0], args[1:]
+	if action != "list" && (len(args) == 0 || strings.HasPrefix(args[0], "-")) {
+		return errors.New(topicsUsage)
+	}
+
+	client, err := g.adminClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	switch action {
+	case "list":
+		topics, err := client.ListTopics()
+		if err != nil {
+			return err
+		}
+		for _, topic := range topics {
+			fmt.Println(topic)
+		}
+		return nil
+	case "describe":
+		return describeTopics(ctx, client, args)
+	case "create":
+		return createTopic(ctx, client, args[0], args[1:])
+	case "alter":
+		return alterTopic(ctx, client, args[0], args[1:])
+	case "delete":
+		return deleteTopic(ctx, client, args[0], args[1:])
+	default:
+		return errors.New(topicsUsage)
+	}
+}
+
+// describeTopics выводит партиции, репликацию и явно заданные параметры топиков
+func describeTopics(ctx context.Context, client *admin.Client, topics []string) error {
+	descriptions, err := client.DescribeTopics(ctx, topics)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tPARTITIONS\tREPLICATION\tCONFIG")
+	for _, d := range descriptions {
+		configs := make([]string, 0, len(d.Config))
+		for k, v := range d.Config {
+			configs = append(configs, k+"="+v)
+		}
+		sort.Strings(configs)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", d.Name, d.Partitions, d.ReplicationFactor, dash(strings.Join(configs, ",")))
+	}
+	return w.Flush()
+}
+
+// createTopic создает топик
+func createTopic(ctx context.Context, client *admin.Client, topic string, args []string) error {
+	config := configFlag{}
+	flags := flag.NewFlagSet("topics create", flag.ExitOnError)
+	partitions := flags.Int("partitions", 1, "число партиций")
+	replication := flags.Int("replication-factor", 1, "фактор репликации")
+	retention := flags.String("retention", "", "время хранения сообщений (168h) или infinite; по умолчанию значение брокера")
+	cleanupPolicy := flags.String("cleanup-policy", "", "политика очистки: delete, compact или compact,delete")
+	flags.Var(config, "config", "параметр топика key=value; можно указать несколько раз")
+	flags.Parse(args)
+
+	spec := admin.TopicSpec{
+		Name:              topic,
+		Partitions:        *partitions,
+		ReplicationFactor: *replication,
+		CleanupPolicy:     *cleanupPolicy,
+		Config:            config,
+	}
+	switch *retention {
+	case "":
+	case "infinite", "-1":
+		spec.Retention = -1
+	default:
+		d, err := time.ParseDuration(*retention)
+		if err != nil {
+			return fmt.Errorf("invalid -retention: %w", err)
+		}
+		spec.Retention = d
+	}
+	if err := client.CreateTopics(ctx, []admin.TopicSpec{spec}); err != nil {
+		return err
+	}
+	fmt.Printf("Topic %s created.\n", topic)
+	return nil
+}
+
+// alterTopic увеличивает число партиций и изменяет параметры топика
+func alterTopic(ctx context.Context, client *admin.Client, topic string, args []string) error {
+	config := configFlag{}
+	flags := flag.NewFlagSet("topics alter", flag.ExitOnError)
+	partitions := flags.Int("partitions", 0, "новое общее число партиций; уменьшить нельзя")
+	flags.Var(config, "config", "параметр топика key=value; можно указать несколько раз")
+	flags.Parse(args)
+
+	if *partitions == 0 && len(config) == 0 {
+		return errors.New("nothing to alter: specify -partitions or -config")
+	}
+	if *partitions > 0 {
+		if err := client.CreatePartitions(ctx, topic, *partitions); err != nil {
+			return err
+		}
+	}
+	if len(config) > 0 {
+		if err := client.AlterConfigs(ctx, topic, config); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Topic %s altered.\n", topic)
+	return nil
+}
+
+// deleteTopic удаляет топик после подтверждения
+func deleteTopic(ctx context.Context, client *admin.Client, topic string, args []string) error {
+	flags := flag.NewFlagSet("topics delete", flag.ExitOnError)
+	autoApprove := flags.Bool("auto-approve", false, "удалить без подтверждения")
+	flags.Parse(args)
+
+	if !*autoApprove && !confirm(fmt.Sprintf("Delete topic %s with all its messages? Only 'yes' will be accepted: ", topic)) {
+		fmt.Println("Delete cancelled.")
+		return nil
+	}
+
+	if err := client.DeleteTopics(ctx, []string{topic}); err != nil {
+		return err
+	}
+	fmt.Printf("Topic %s deleted.\n", topic)
+	return nil
+}
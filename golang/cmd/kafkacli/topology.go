@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kafka-examples/golang/src/topology"
+)
+
+// runTopology выполняет topology plan и topology apply
+func runTopology(g *globals, args []string) error {
+	if len(args) == 0 || (args[0] != "plan" && args[0] != "apply") {
+		return fmt.Errorf("usage: kafkacli topology plan|apply -f topology.yaml")
+	}
+	action := args[0]
+
+	flags := flag.NewFlagSet("topology "+action, flag.ExitOnError)
+	file := flags.String("f", "topology.yaml", "файл топологии")
+	autoApprove := flags.Bool("auto-approve", false, "применить без подтверждения")
+	flags.Parse(args[1:])
+
+	t, err := topology.Load(*file)
+	if err != nil {
+		return err
+	}
+
+	client, err := g.adminClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	registry, err := g.registryClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	planner := topology.NewPlanner(client, registry)
+	plan, err := planner.Plan(ctx, t)
+	if err != nil {
+		return err
+	}
+	plan.Write(os.Stdout)
+
+	if action == "plan" || plan.Empty() {
+		return nil
+	}
+
+	if !*autoApprove && !confirm("Apply these changes? Only 'yes' will be accepted: ") {
+		fmt.Println("Apply cancelled.")
+		return nil
+	}
+
+	if err := planner.Apply(ctx, plan); err != nil {
+		return err
+	}
+	fmt.Println("Apply complete.")
+	return nil
+}
+
+// confirm запрашивает подтверждение у пользователя
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,6 +16,9 @@ import (
 func main() {
 	// Создаем логгер
 	logger := log.New(os.Stdout, "advanced-consumer: ", log.LstdFlags)
+
+	// Структурированный логгер для библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "advanced-consumer")
 	logger.Println("Запуск продвинутого консьюмера...")
 
 	// Названия топиков, которые хотим слушать
@@ -21,16 +26,21 @@ func main() {
 
 	// Создаем расширенную конфигурацию
 	config := map[string]string{
-		"group.id":          "advanced-consumer-group",
-		"auto.offset.reset": "earliest",
-		"fetch.wait.max.ms": "100",
-		"fetch.error.backoff.ms": "500",
-		"enable.auto.commit": "true",
+		"group.id":                "advanced-consumer-group",
+		"auto.offset.reset":       "earliest",
+		"fetch.wait.max.ms":       "100",
+		"fetch.error.backoff.ms":  "500",
+		"enable.auto.commit":      "true",
 		"auto.commit.interval.ms": "5000",
 	}
 
 	// Создаем консьюмера
-	consumer, err := kafkalib.NewConsumer(topics, config, logger)
+	consumer, err := kafkalib.NewConsumer(topics, config, kafkaLogger,
+		kafkalib.WithLibrdkafkaLogs(),      // Внутренние логи librdkafka пишутся в тот же логгер
+		kafkalib.WithPartitionWorkers(100), // Каждая партиция обрабатывается своей горутиной
+		kafkalib.WithStaticMembership(""),  // Перезапуск пода не вызывает перебалансировку
+		kafkalib.WithShutdownTimeout(10*time.Second),
+	)
 	if err != nil {
 		logger.Fatalf("Ошибка при создании консьюмера: %v", err)
 	}
@@ -47,25 +57,36 @@ func main() {
 		consumer.Stop()
 	}()
 
+	// Сквозная логика подключается через middleware: восстановление после паники
+	// и ограничение времени обработки одного сообщения
+	consumer.Use(
+		kafkalib.Recover(kafkaLogger),
+		kafkalib.Timeout(5*time.Second),
+	)
+
 	// Создаем обработчик сообщений с расширенной логикой
-	messageHandler := func(message *kafka.Message) bool {
+	messageHandler := func(ctx context.Context, message *kafka.Message) error {
 		logger.Printf("Обработка сообщения из топика %s: %s",
 			*message.TopicPartition.Topic, string(message.Value))
-		
+
 		// Подробная информация о сообщении
 		logger.Printf("  Детали: Партиция=%d, Смещение=%v, Ключ=%s",
 			message.TopicPartition.Partition, message.TopicPartition.Offset,
 			string(message.Key))
-		
-		// Имитируем обработку сообщения
-		time.Sleep(100 * time.Millisecond)
-		
+
+		// Имитируем обработку сообщения с учетом таймаута
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
 		logger.Printf("Сообщение успешно обработано")
-		return true
+		return nil
 	}
 
 	// Запускаем консьюмера
-	consumer.Start(messageHandler)
+	consumer.Run(context.Background(), messageHandler)
 
 	logger.Println("Консьюмер остановлен")
-}
\ No newline at end of file
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -13,6 +14,9 @@ import (
 func main() {
 	// Создаем логгер
 	logger := log.New(os.Stdout, "advanced-producer: ", log.LstdFlags)
+
+	// Структурированный логгер для библиотеки
+	kafkaLogger := kafka.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "advanced-producer")
 	logger.Println("Запуск продвинутого продюсера...")
 
 	// Название топика
@@ -21,7 +25,7 @@ func main() {
 	// Создаем продюсера с расширенной конфигурацией
 	producer, err := kafka.NewProducer(topic, map[string]string{
 		"acks": "all", // Ожидаем подтверждения от всех реплик
-	}, logger)
+	}, kafkaLogger)
 	if err != nil {
 		logger.Fatalf("Ошибка при создании продюсера: %v", err)
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,6 +14,9 @@ import (
 func main() {
 	// Создаем логгер
 	logger := log.New(os.Stdout, "basic-consumer: ", log.LstdFlags)
+
+	// Структурированный логгер для библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "basic-consumer")
 	logger.Println("Запуск консьюмера...")
 
 	// Название топика
@@ -25,7 +29,7 @@ func main() {
 	}
 
 	// Создаем консьюмера
-	consumer, err := kafkalib.NewConsumer(topics, config, logger)
+	consumer, err := kafkalib.NewConsumer(topics, config, kafkaLogger)
 	if err != nil {
 		logger.Fatalf("Ошибка при создании консьюмера: %v", err)
 	}
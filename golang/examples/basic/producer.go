@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -12,13 +13,16 @@ import (
 func main() {
 	// Создаем логгер
 	logger := log.New(os.Stdout, "basic-producer: ", log.LstdFlags)
+
+	// Структурированный логгер для библиотеки
+	kafkaLogger := kafka.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "basic-producer")
 	logger.Println("Запуск продюсера...")
 
 	// Название топика
 	topic := "basic-topic"
 
 	// Создаем продюсера
-	producer, err := kafka.NewProducer(topic, nil, logger)
+	producer, err := kafka.NewProducer(topic, nil, kafkaLogger)
 	if err != nil {
 		logger.Fatalf("Ошибка при создании продюсера: %v", err)
 	}
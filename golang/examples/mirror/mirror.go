@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+	"github.com/kafka-examples/golang/src/mirror"
+)
+
+func main() {
+	// Создаем логгер
+	logger := log.New(os.Stdout, "mirror: ", log.LstdFlags)
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "mirror")
+
+	// Адреса кластеров задаются переменными окружения; по умолчанию оба
+	// указывают на локальный кластер, а топики копируются с префиксом
+	source := getEnv("SOURCE_BROKERS", "kafka:29092")
+	target := getEnv("TARGET_BROKERS", "kafka:29092")
+
+	m, err := mirror.New(mirror.Config{
+		Name:        "staging-to-production",
+		Topics:      getEnv("MIRROR_TOPICS", "advanced-topic|partitioned-topic-go"),
+		Source:      map[string]string{"bootstrap.servers": source},
+		Target:      map[string]string{"bootstrap.servers": target},
+		TopicPrefix: getEnv("MIRROR_PREFIX", "staging."),
+	}, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании зеркала: %v", err)
+	}
+	defer m.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Printf("Копирование топиков из %s в %s. Нажмите Ctrl+C для остановки", source, target)
+	if err := m.Run(ctx); err != nil && ctx.Err() == nil {
+		logger.Fatalf("Ошибка при копировании: %v", err)
+	}
+
+	// Контрольная точка связывает позиции исходной и целевой партиций
+	if cp, ok := m.Checkpoint("advanced-topic", 0); ok {
+		logger.Printf("Контрольная точка %s [%d]: смещение %d соответствует смещению %d в %s",
+			cp.SourceTopic, cp.Partition, cp.SourceOffset, cp.TargetOffset, cp.TargetTopic)
+	}
+	logger.Println("Зеркало остановлено")
+}
+
+// getEnv возвращает значение переменной окружения или значение по умолчанию
+func getEnv(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
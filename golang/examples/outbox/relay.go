@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+	"github.com/kafka-examples/golang/src/outbox"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	// Создаем логгер
+	logger := log.New(os.Stdout, "outbox: ", log.LstdFlags)
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "outbox")
+
+	// Локальная база SQLite; в production используется Postgres (outbox.Postgres)
+	db, err := sql.Open("sqlite3", "outbox.db?_busy_timeout=5000")
+	if err != nil {
+		logger.Fatalf("Ошибка при открытии базы данных: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	table := outbox.Table{Name: "outbox", Dialect: outbox.SQLite}
+	if err := table.Create(ctx, db); err != nil {
+		logger.Fatalf("Ошибка при создании таблицы outbox: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS orders (id INTEGER PRIMARY KEY, amount INTEGER)"); err != nil {
+		logger.Fatalf("Ошибка при создании таблицы orders: %v", err)
+	}
+
+	relay, err := outbox.NewRelay(db, outbox.Config{Table: table}, nil, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании relay: %v", err)
+	}
+	defer relay.Close()
+
+	go func() {
+		if err := relay.Run(ctx); err != nil && ctx.Err() == nil {
+			logger.Printf("Relay остановлен: %v", err)
+		}
+	}()
+
+	// Заказ и событие о нем сохраняются в одной транзакции
+	for i := 1; i <= 5 && ctx.Err() == nil; i++ {
+		if err := createOrder(ctx, db, table, i*100); err != nil {
+			logger.Fatalf("Ошибка при создании заказа: %v", err)
+		}
+		relay.Notify()
+		time.Sleep(time.Second)
+	}
+
+	<-ctx.Done()
+	logger.Println("Остановка")
+}
+
+// createOrder сохраняет заказ и событие OrderCreated в одной транзакции
+func createOrder(ctx context.Context, db *sql.DB, table outbox.Table, amount int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO orders (amount) VALUES (?)", amount)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	err = table.Insert(ctx, tx, outbox.Message{
+		Topic:   "orders",
+		Key:     []byte(fmt.Sprintf("order-%d", id)),
+		Value:   []byte(fmt.Sprintf(`{"id":%d,"amount":%d}`, id, amount)),
+		Headers: map[string]string{"event-type": "OrderCreated"},
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"strconv"
@@ -10,12 +12,16 @@ import (
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
 )
 
 func main() {
 	// Создаем логгер
 	logger := log.New(os.Stdout, "partitioned-consumer: ", log.LstdFlags)
-	
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "partitioned-consumer")
+
 	// Получаем номер партиции из аргументов командной строки или используем 0 по умолчанию
 	partition := 0
 	if len(os.Args) > 1 {
@@ -26,27 +32,24 @@ func main() {
 		partition = partArg
 	}
 
-	logger.Printf("Запуск консьюмера для партиции %d...", partition)
+	// Начальная позиция: beginning (по умолчанию), end, номер смещения или время в формате RFC3339
+	start := "beginning"
+	if len(os.Args) > 2 {
+		start = os.Args[2]
+	}
+
+	logger.Printf("Запуск консьюмера для партиции %d с позиции %s...", partition, start)
 
 	// Название топика
 	topic := "partitioned-topic-go"
 
 	// Создаем конфигурацию
-	config := kafka.ConfigMap{
-		"bootstrap.servers":  "kafka:29092",
+	config := map[string]string{
 		"group.id":           fmt.Sprintf("partitioned-consumer-group-%d", partition),
-		"auto.offset.reset":  "earliest",
 		"enable.auto.commit": "true",
 	}
 
-	// Создаем консьюмера
-	consumer, err := kafka.NewConsumer(&config)
-	if err != nil {
-		logger.Fatalf("Ошибка при создании консьюмера: %v", err)
-	}
-	defer consumer.Close()
-
-	// Создаем список партиций для конкретного топика
+	// Назначаем консьюмеру указанную партицию вместо подписки на топик
 	topicPartitions := []kafka.TopicPartition{
 		{
 			Topic:     &topic,
@@ -55,47 +58,49 @@ func main() {
 		},
 	}
 
-	// Назначаем консьюмеру указанные партиции конкретных топиков
-	err = consumer.Assign(topicPartitions)
+	var assignment kafkalib.Option
+	switch start {
+	case "beginning":
+		assignment = kafkalib.WithAssignment(topicPartitions)
+	case "end":
+		topicPartitions[0].Offset = kafka.OffsetEnd
+		assignment = kafkalib.WithAssignment(topicPartitions)
+	default:
+		if offset, err := strconv.ParseInt(start, 10, 64); err == nil {
+			topicPartitions[0].Offset = kafka.Offset(offset)
+			assignment = kafkalib.WithAssignment(topicPartitions)
+		} else if t, err := time.Parse(time.RFC3339, start); err == nil {
+			assignment = kafkalib.WithAssignmentAtTime(topicPartitions, t)
+		} else {
+			logger.Fatalf("Некорректная начальная позиция: %s", start)
+		}
+	}
+
+	// Создаем консьюмера
+	consumer, err := kafkalib.NewConsumer(nil, config, kafkaLogger, assignment)
 	if err != nil {
-		logger.Fatalf("Ошибка при назначении партиций: %v", err)
+		logger.Fatalf("Ошибка при создании консьюмера: %v", err)
 	}
+	defer consumer.Close()
 
-	logger.Printf("Успешно подписались на топик %s, партиция %d", topic, partition)
+	positions, err := consumer.Position()
+	if err != nil {
+		logger.Fatalf("Ошибка при получении позиции: %v", err)
+	}
+	logger.Printf("Успешно назначена партиция %d топика %s, позиция: %v", partition, topic, positions)
 
 	// Обработчик CTRL+C для грациозного завершения
-	sigchan := make(chan os.Signal, 1)
-	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Флаг для контроля работы цикла
-	running := true
-
-	// Запускаем обработку в отдельной горутине
-	go func() {
-		<-sigchan
-		logger.Println("Получен сигнал завершения")
-		running = false
-	}()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Основной цикл чтения
-	for running {
-		// Получаем сообщение с таймаутом
-		msg, err := consumer.ReadMessage(100 * time.Millisecond)
-		
-		if err != nil {
-			// Проверяем, является ли ошибка таймаутом
-			if err.(kafka.Error).Code() == kafka.ErrTimedOut {
-				continue // Таймаут - не ошибка, продолжаем
-			}
-			logger.Printf("Ошибка при чтении сообщения: %v", err)
-			continue
-		}
-
+	consumer.Run(ctx, func(ctx context.Context, msg *kafka.Message) error {
 		// Выводим полученное сообщение
 		logger.Printf("Получено сообщение из топика %s [%d] со смещением %v:\n  Ключ: %s\n  Значение: %s",
 			*msg.TopicPartition.Topic, msg.TopicPartition.Partition, msg.TopicPartition.Offset,
 			string(msg.Key), string(msg.Value))
-	}
+		return nil
+	})
 
 	logger.Println("Консьюмер остановлен")
-}
\ No newline at end of file
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kafka-examples/golang/src/admin"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+func main() {
+	// Создаем логгер
+	logger := log.New(os.Stdout, "create-topic: ", log.LstdFlags)
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "create-topic")
+
+	// Создаем административный клиент
+	client, err := admin.NewClient(nil, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании административного клиента: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Топик создается, если его нет, а существующий приводится к спецификации;
+	// повторный запуск ничего не меняет
+	changes, err := client.EnsureTopics(ctx, []admin.TopicSpec{
+		{
+			Name:              "partitioned-topic-go",
+			Partitions:        3,
+			ReplicationFactor: 1,
+			Retention:         7 * 24 * time.Hour,
+			CleanupPolicy:     "delete",
+		},
+	})
+	if err != nil {
+		logger.Fatalf("Ошибка при создании топика: %v", err)
+	}
+
+	if len(changes) == 0 {
+		logger.Println("Топик уже соответствует спецификации")
+	}
+	for _, change := range changes {
+		logger.Println(change)
+	}
+
+	// Проверка созданного топика
+	descriptions, err := client.DescribeTopics(ctx, []string{"partitioned-topic-go"})
+	if err != nil {
+		logger.Fatalf("Ошибка при получении описания топика: %v", err)
+	}
+	for _, d := range descriptions {
+		logger.Printf("Топик %s: партиций %d, фактор репликации %d, параметры %v",
+			d.Name, d.Partitions, d.ReplicationFactor, d.Config)
+	}
+}
@@ -3,38 +3,31 @@ package main
 import (
 	"context"
 	"fmt"
-	"hash/crc32"
 	"log"
+	"log/slog"
 	"os"
 	"time"
 
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafka "github.com/kafka-examples/golang/src/kafka"
 )
 
-// getPartition вычисляет партицию на основе ключа
-func getPartition(key string, partitionCount int) int {
-	// Используем crc32 для получения хеша ключа
-	hash := crc32.ChecksumIEEE([]byte(key))
-	// Преобразуем хеш в положительное число и получаем остаток от деления на количество партиций
-	return int(hash) % partitionCount
-}
-
 func main() {
 	// Создаем логгер
 	logger := log.New(os.Stdout, "partitioned-producer: ", log.LstdFlags)
 	logger.Println("Запуск продюсера с определенными партициями...")
 
-	// Название топика и количество партиций
+	// Логгер библиотеки
+	kafkaLogger := kafka.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "partitioned-producer")
+
+	// Название топика; число партиций продюсер получает из метаданных
 	topic := "partitioned-topic-go"
-	partitionCount := 3
 
-	// Создаем конфигурацию
-	config := kafka.ConfigMap{
-		"bootstrap.servers": "kafka:29092",
-	}
+	// Партиция вычисляется по хешу murmur2 ключа так же, как в Java-клиенте,
+	// поэтому сообщения с одинаковым ключом попадают в одну партицию
+	partitioner := kafka.Murmur2Partitioner()
 
 	// Создаем продюсера
-	producer, err := kafka.NewProducer(&config)
+	producer, err := kafka.NewProducer(topic, nil, kafkaLogger, kafka.WithPartitioner(partitioner))
 	if err != nil {
 		logger.Fatalf("Ошибка при создании продюсера: %v", err)
 	}
@@ -43,25 +36,9 @@ func main() {
 	// Запускаем обработку отчетов о доставке
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	producer.ProcessDeliveryReports(ctx)
 
-	go func() {
-		for {
-			select {
-			case e := <-producer.Events():
-				switch ev := e.(type) {
-				case *kafka.Message:
-					if ev.TopicPartition.Error != nil {
-						logger.Printf("Ошибка доставки: %v", ev.TopicPartition.Error)
-					} else {
-						logger.Printf("Сообщение доставлено в %s [%d] со смещением %v",
-							*ev.TopicPartition.Topic, ev.TopicPartition.Partition, ev.TopicPartition.Offset)
-					}
-				}
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
+	logger.Printf("Топик %s содержит %d партиций", topic, producer.PartitionCount())
 
 	// Список ключей для отправки
 	keys := []string{
@@ -72,23 +49,12 @@ func main() {
 
 	// Отправляем сообщения с разными ключами
 	for _, key := range keys {
-		// Вычисляем партицию на основе ключа
-		partition := getPartition(key, partitionCount)
-		
-		// Создаем сообщение с указанием партиции
-		message := &kafka.Message{
-			TopicPartition: kafka.TopicPartition{
-				Topic:     &topic,
-				Partition: int32(partition),
-			},
-			Key:   []byte(key),
-			Value: []byte(fmt.Sprintf("Сообщение с ключом %s (время: %s)", key, time.Now().Format(time.RFC3339))),
-		}
-
+		partition := partitioner.Partition(topic, []byte(key), producer.PartitionCount())
 		logger.Printf("Отправка сообщения с ключом %s в партицию %d", key, partition)
-		
+
 		// Отправляем сообщение
-		if err := producer.Produce(message, nil); err != nil {
+		value := fmt.Sprintf("Сообщение с ключом %s (время: %s)", key, time.Now().Format(time.RFC3339))
+		if err := producer.Send(value, key); err != nil {
 			logger.Printf("Ошибка при отправке сообщения: %v", err)
 		}
 
@@ -97,10 +63,7 @@ func main() {
 	}
 
 	// Ждем, пока все сообщения будут отправлены
-	remaining := producer.Flush(10000)
-	if remaining > 0 {
-		logger.Printf("Предупреждение: %d сообщений не были отправлены", remaining)
-	}
+	producer.Flush()
 
 	logger.Println("Все сообщения отправлены!")
-}
\ No newline at end of file
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+	"github.com/kafka-examples/golang/src/rpc"
+)
+
+func main() {
+	// Создаем логгер
+	logger := log.New(os.Stdout, "requester: ", log.LstdFlags)
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelWarn, false).With("app", "rpc-requester")
+
+	// Каждый экземпляр читает ответы из своей партиции топика rpc-replies
+	partition, err := strconv.Atoi(getEnv("REPLY_PARTITION", "0"))
+	if err != nil {
+		logger.Fatalf("Некорректный REPLY_PARTITION: %v", err)
+	}
+
+	requester, err := rpc.NewRequester(rpc.RequesterConfig{
+		ReplyTopic:     "rpc-replies",
+		ReplyPartition: int32(partition),
+		Timeout:        5 * time.Second,
+	}, map[string]string{"bootstrap.servers": "kafka:29092"}, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании запрашивающего: %v", err)
+	}
+	defer requester.Close()
+
+	// Последний запрос пустой: отвечающий вернет ошибку
+	for _, text := range []string{"hello", "request-reply", ""} {
+		started := time.Now()
+		reply, err := requester.Request(context.Background(), "rpc-requests", kafkalib.Record{
+			Key:   []byte(fmt.Sprintf("key-%d", started.UnixNano())),
+			Value: []byte(text),
+		})
+
+		var replyErr *rpc.ReplyError
+		switch {
+		case errors.As(err, &replyErr):
+			logger.Printf("Запрос %q: ошибка обработки: %s", text, replyErr.Message)
+		case errors.Is(err, rpc.ErrTimeout):
+			logger.Printf("Запрос %q: ответ не получен, запущен ли responder.go?", text)
+		case err != nil:
+			logger.Fatalf("Ошибка при отправке запроса: %v", err)
+		default:
+			logger.Printf("Запрос %q: ответ %q за %s", text, reply.Value, time.Since(started))
+		}
+	}
+}
+
+// getEnv возвращает значение переменной окружения или значение по умолчанию
+func getEnv(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
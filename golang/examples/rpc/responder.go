@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+	"github.com/kafka-examples/golang/src/rpc"
+)
+
+func main() {
+	// Создаем логгер
+	logger := log.New(os.Stdout, "responder: ", log.LstdFlags)
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "rpc-responder")
+
+	config := map[string]string{"bootstrap.servers": "kafka:29092"}
+
+	responder, err := rpc.NewResponder(config, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании отвечающего: %v", err)
+	}
+	defer responder.Close()
+
+	consumerConfig := map[string]string{
+		"bootstrap.servers": "kafka:29092",
+		"group.id":          "rpc-responder-group",
+	}
+	consumer, err := kafkalib.NewConsumer([]string{"rpc-requests"}, consumerConfig, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании консьюмера: %v", err)
+	}
+	defer consumer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Ответ - строка запроса в верхнем регистре; пустой запрос считается ошибкой
+	handler := responder.Handler(func(ctx context.Context, msg *kafka.Message) (kafkalib.Record, error) {
+		if len(msg.Value) == 0 {
+			return kafkalib.Record{}, errors.New("empty request")
+		}
+		return kafkalib.Record{Key: msg.Key, Value: []byte(strings.ToUpper(string(msg.Value)))}, nil
+	})
+
+	logger.Println("Ожидание запросов. Нажмите Ctrl+C для остановки")
+	if err := consumer.Run(ctx, handler); err != nil && ctx.Err() == nil {
+		logger.Fatalf("Ошибка при обработке запросов: %v", err)
+	}
+	logger.Println("Отвечающий остановлен")
+}
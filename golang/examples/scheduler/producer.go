@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+func main() {
+	// Создаем логгер
+	logger := log.New(os.Stdout, "producer: ", log.LstdFlags)
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelWarn, false).With("app", "scheduler-producer")
+
+	// Сообщения будут доставлены в basic-topic: их можно прочитать examples/basic/consumer.go
+	producer, err := kafkalib.NewProducer("basic-topic", map[string]string{"bootstrap.servers": "kafka:29092"}, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании продюсера: %v", err)
+	}
+	defer producer.Close()
+
+	for _, delay := range []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute} {
+		at := time.Now().Add(delay)
+		record := kafkalib.Record{
+			Key:   []byte(fmt.Sprintf("delayed-%s", delay)),
+			Value: []byte(fmt.Sprintf("Сообщение, отложенное до %s", at.Format(time.TimeOnly))),
+		}
+		if err := producer.SendAt(context.Background(), record, at); err != nil {
+			logger.Fatalf("Ошибка при отправке сообщения: %v", err)
+		}
+		logger.Printf("Сообщение будет доставлено в %s", at.Format(time.TimeOnly))
+	}
+
+	// Дожидаемся отправки сообщений в топики задержки
+	producer.Flush()
+}
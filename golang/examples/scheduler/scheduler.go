@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+	"github.com/kafka-examples/golang/src/scheduler"
+)
+
+func main() {
+	// Создаем логгер
+	logger := log.New(os.Stdout, "scheduler: ", log.LstdFlags)
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "scheduler")
+
+	// Топики задержки delay-10s ... delay-24h создаются при запуске
+	s, err := scheduler.New(scheduler.Config{}, map[string]string{"bootstrap.servers": "kafka:29092"}, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании планировщика: %v", err)
+	}
+	defer s.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Println("Пересылка отложенных сообщений. Нажмите Ctrl+C для остановки")
+	if err := s.Run(ctx); err != nil && ctx.Err() == nil {
+		logger.Fatalf("Ошибка при пересылке: %v", err)
+	}
+	logger.Println("Планировщик остановлен")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+	"github.com/kafka-examples/golang/src/streams"
+)
+
+func main() {
+	// Создаем логгер
+	logger := log.New(os.Stdout, "wordcount: ", log.LstdFlags)
+
+	// Логгер библиотеки
+	kafkaLogger := kafkalib.NewLogger(os.Stdout, slog.LevelInfo, false).With("app", "wordcount")
+
+	app, err := streams.New(streams.Config{
+		ApplicationID: "wordcount",
+		StateDir:      getEnv("STATE_DIR", "/tmp/kafka-streams"),
+	}, map[string]string{"bootstrap.servers": "kafka:29092"}, kafkaLogger)
+	if err != nil {
+		logger.Fatalf("Ошибка при создании приложения: %v", err)
+	}
+	defer app.Close()
+
+	// Строки из streams-input разбиваются на слова; ключ записи - слово, поэтому
+	// перед подсчетом записи перепартиционируются через wordcount-word-counts-repartition
+	counts := app.Stream("streams-input").
+		FlatMap(func(r streams.Record) ([]streams.Record, error) {
+			var words []streams.Record
+			for _, word := range bytes.Fields(bytes.ToLower(r.Value)) {
+				words = append(words, streams.Record{Key: word, Value: word, Timestamp: r.Timestamp})
+			}
+			return words, nil
+		}).
+		GroupByKey().
+		Count("word-counts")
+
+	// Каждое изменение счетчика отправляется в streams-word-counts
+	counts.ToStream().To("streams-word-counts")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Println("Подсчет слов из streams-input. Нажмите Ctrl+C для остановки")
+	if err := app.Run(ctx); err != nil && ctx.Err() == nil {
+		logger.Fatalf("Ошибка при обработке: %v", err)
+	}
+
+	if count, ok := counts.Get([]byte("kafka")); ok {
+		logger.Printf("Слово kafka встретилось %s раз", count)
+	}
+	logger.Println("Приложение остановлено")
+}
+
+// getEnv возвращает значение переменной окружения или значение по умолчанию
+func getEnv(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// anyACLFilter выбирает все ACL кластера
+var anyACLFilter = kafka.ACLBindingFilter{
+	Type:                kafka.ResourceAny,
+	ResourcePatternType: kafka.ResourcePatternTypeAny,
+	Operation:           kafka.ACLOperationAny,
+	PermissionType:      kafka.ACLPermissionTypeAny,
+}
+
+// ListACLs возвращает все ACL кластера
+func (c *Client) ListACLs(ctx context.Context) (kafka.ACLBindings, error) {
+	result, err := c.admin.DescribeACLs(ctx, anyACLFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe ACLs: %w", err)
+	}
+	if result.Error.Code() != kafka.ErrNoError {
+		return nil, fmt.Errorf("failed to describe ACLs: %w", result.Error)
+	}
+	return result.ACLBindings, nil
+}
+
+// CreateACLs создает ACL
+func (c *Client) CreateACLs(ctx context.Context, acls kafka.ACLBindings) error {
+	if len(acls) == 0 {
+		return nil
+	}
+
+	results, err := c.admin.CreateACLs(ctx, acls)
+	if err != nil {
+		return fmt.Errorf("failed to create ACLs: %w", err)
+	}
+
+	var errs []error
+	for i, res := range results {
+		if res.Error.Code() != kafka.ErrNoError {
+			errs = append(errs, fmt.Errorf("failed to create ACL %s: %w", FormatACL(acls[i]), res.Error))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	c.logger.Info("ACLs created", slog.Int("count", len(acls)))
+	return nil
+}
+
+// DeleteACLs удаляет ACL, точно совпадающие с переданными
+func (c *Client) DeleteACLs(ctx context.Context, acls kafka.ACLBindings) error {
+	if len(acls) == 0 {
+		return nil
+	}
+
+	results, err := c.admin.DeleteACLs(ctx, kafka.ACLBindingFilters(acls))
+	if err != nil {
+		return fmt.Errorf("failed to delete ACLs: %w", err)
+	}
+
+	var errs []error
+	for i, res := range results {
+		if res.Error.Code() != kafka.ErrNoError {
+			errs = append(errs, fmt.Errorf("failed to delete ACL %s: %w", FormatACL(acls[i]), res.Error))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	c.logger.Info("ACLs deleted", slog.Int("count", len(acls)))
+	return nil
+}
+
+// FormatACL описывает ACL в одну строку
+func FormatACL(acl kafka.ACLBinding) string {
+	return fmt.Sprintf("%s %s %s on %s:%s:%s from %s",
+		acl.Principal, acl.PermissionType, acl.Operation,
+		acl.Type, acl.ResourcePatternType, acl.Name, acl.Host)
+}
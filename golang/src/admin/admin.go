@@ -0,0 +1,241 @@
+// Package admin содержит операции управления топиками Kafka поверх kafka.AdminClient
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// operationTimeout - время ожидания применения операции на брокерах
+const operationTimeout = 30 * time.Second
+
+// Client выполняет административные операции с топиками
+type Client struct {
+	admin  *kafka.AdminClient
+	logger *slog.Logger
+}
+
+// NewClient создает административный клиент; config и opts задаются так же,
+// как для Producer и Consumer. Если logger равен nil, используется slog.Default()
+func NewClient(config map[string]string, logger *slog.Logger, opts ...kafkalib.Option) (*Client, error) {
+	a, err := kafkalib.NewAdminClient(config, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Client{admin: a, logger: logger}, nil
+}
+
+// AdminClient возвращает исходный kafka.AdminClient для операций, не покрытых Client
+func (c *Client) AdminClient() *kafka.AdminClient {
+	return c.admin
+}
+
+// Close закрывает соединение с Kafka
+func (c *Client) Close() {
+	c.admin.Close()
+}
+
+// TopicSpec описывает желаемое состояние топика
+type TopicSpec struct {
+	Name              string
+	Partitions        int
+	ReplicationFactor int
+	// Retention - время хранения сообщений (retention.ms); 0 - значение брокера,
+	// отрицательное значение - хранить бессрочно
+	Retention time.Duration
+	// CleanupPolicy - политика очистки (cleanup.policy): delete, compact или compact,delete
+	CleanupPolicy string
+	// Config - прочие параметры топика; имеют приоритет над Retention и CleanupPolicy
+	Config map[string]string
+}
+
+// Configs возвращает все параметры топика, заданные в спецификации
+func (s TopicSpec) Configs() map[string]string {
+	configs := make(map[string]string)
+	if s.Retention < 0 {
+		configs["retention.ms"] = "-1"
+	} else if s.Retention > 0 {
+		configs["retention.ms"] = strconv.FormatInt(s.Retention.Milliseconds(), 10)
+	}
+	if s.CleanupPolicy != "" {
+		configs["cleanup.policy"] = s.CleanupPolicy
+	}
+	for k, v := range s.Config {
+		configs[k] = v
+	}
+	return configs
+}
+
+// TopicDescription - текущее состояние топика
+type TopicDescription struct {
+	Name              string
+	Partitions        int
+	ReplicationFactor int
+	// Config - параметры, явно заданные для топика (без значений брокера по умолчанию)
+	Config map[string]string
+}
+
+// ErrTopicNotFound возвращается DescribeTopics для несуществующего топика
+var ErrTopicNotFound = errors.New("topic not found")
+
+// CreateTopics создает топики по спецификациям
+func (c *Client) CreateTopics(ctx context.Context, specs []TopicSpec) error {
+	topics := make([]kafka.TopicSpecification, len(specs))
+	for i, spec := range specs {
+		topics[i] = kafka.TopicSpecification{
+			Topic:             spec.Name,
+			NumPartitions:     spec.Partitions,
+			ReplicationFactor: spec.ReplicationFactor,
+			Config:            spec.Configs(),
+		}
+	}
+
+	results, err := c.admin.CreateTopics(ctx, topics, kafka.SetAdminOperationTimeout(operationTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to create topics: %w", err)
+	}
+	if err := topicResultsError("create topic", results); err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		c.logger.Info("topic created", slog.String(kafkalib.LogKeyTopic, spec.Name),
+			slog.Int("partitions", spec.Partitions), slog.Int("replication_factor", spec.ReplicationFactor))
+	}
+	return nil
+}
+
+// DeleteTopics удаляет топики
+func (c *Client) DeleteTopics(ctx context.Context, topics []string) error {
+	results, err := c.admin.DeleteTopics(ctx, topics, kafka.SetAdminOperationTimeout(operationTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to delete topics: %w", err)
+	}
+	if err := topicResultsError("delete topic", results); err != nil {
+		return err
+	}
+
+	for _, topic := range topics {
+		c.logger.Info("topic deleted", slog.String(kafkalib.LogKeyTopic, topic))
+	}
+	return nil
+}
+
+// CreatePartitions увеличивает число партиций топика до partitions
+func (c *Client) CreatePartitions(ctx context.Context, topic string, partitions int) error {
+	spec := []kafka.PartitionsSpecification{{Topic: topic, IncreaseTo: partitions}}
+	results, err := c.admin.CreatePartitions(ctx, spec, kafka.SetAdminOperationTimeout(operationTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to create partitions: %w", err)
+	}
+	if err := topicResultsError("create partitions", results); err != nil {
+		return err
+	}
+
+	c.logger.Info("partitions created", slog.String(kafkalib.LogKeyTopic, topic), slog.Int("partitions", partitions))
+	return nil
+}
+
+// DescribeTopics возвращает число партиций, фактор репликации и явно заданные параметры топиков.
+// Для несуществующего топика возвращается ошибка, обернутая в ErrTopicNotFound
+func (c *Client) DescribeTopics(ctx context.Context, topics []string) ([]TopicDescription, error) {
+	result, err := c.admin.DescribeTopics(ctx, kafka.NewTopicCollectionOfTopicNames(topics))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topics: %w", err)
+	}
+
+	descriptions := make([]TopicDescription, 0, len(result.TopicDescriptions))
+	resources := make([]kafka.ConfigResource, 0, len(result.TopicDescriptions))
+	for _, td := range result.TopicDescriptions {
+		switch td.Error.Code() {
+		case kafka.ErrNoError:
+		case kafka.ErrUnknownTopicOrPart, kafka.ErrUnknownTopic:
+			return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, td.Name)
+		default:
+			return nil, fmt.Errorf("failed to describe topic %s: %w", td.Name, td.Error)
+		}
+
+		description := TopicDescription{Name: td.Name, Partitions: len(td.Partitions)}
+		if len(td.Partitions) > 0 {
+			description.ReplicationFactor = len(td.Partitions[0].Replicas)
+		}
+		descriptions = append(descriptions, description)
+		resources = append(resources, kafka.ConfigResource{Type: kafka.ResourceTopic, Name: td.Name})
+	}
+
+	if len(resources) == 0 {
+		return descriptions, nil
+	}
+
+	configs, err := c.admin.DescribeConfigs(ctx, resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic configs: %w", err)
+	}
+	index := make(map[string]int, len(descriptions))
+	for i, description := range descriptions {
+		index[description.Name] = i
+	}
+	for _, res := range configs {
+		if res.Error.Code() != kafka.ErrNoError {
+			return nil, fmt.Errorf("failed to describe config of topic %s: %w", res.Name, res.Error)
+		}
+		config := make(map[string]string)
+		for name, entry := range res.Config {
+			if entry.Source == kafka.ConfigSourceDynamicTopic {
+				config[name] = entry.Value
+			}
+		}
+		descriptions[index[res.Name]].Config = config
+	}
+
+	sort.Slice(descriptions, func(i, j int) bool { return descriptions[i].Name < descriptions[j].Name })
+	return descriptions, nil
+}
+
+// AlterConfigs устанавливает параметры топика; остальные параметры не изменяются
+func (c *Client) AlterConfigs(ctx context.Context, topic string, configs map[string]string) error {
+	resource := kafka.ConfigResource{Type: kafka.ResourceTopic, Name: topic}
+	for name, value := range configs {
+		resource.Config = append(resource.Config, kafka.ConfigEntry{
+			Name:                 name,
+			Value:                value,
+			IncrementalOperation: kafka.AlterConfigOpTypeSet,
+		})
+	}
+
+	results, err := c.admin.IncrementalAlterConfigs(ctx, []kafka.ConfigResource{resource})
+	if err != nil {
+		return fmt.Errorf("failed to alter configs of topic %s: %w", topic, err)
+	}
+	for _, res := range results {
+		if res.Error.Code() != kafka.ErrNoError {
+			return fmt.Errorf("failed to alter configs of topic %s: %w", res.Name, res.Error)
+		}
+	}
+
+	c.logger.Info("topic configs altered", slog.String(kafkalib.LogKeyTopic, topic), slog.Any("configs", configs))
+	return nil
+}
+
+// topicResultsError объединяет ошибки результатов операций над топиками
+func topicResultsError(operation string, results []kafka.TopicResult) error {
+	var errs []error
+	for _, res := range results {
+		if res.Error.Code() != kafka.ErrNoError {
+			errs = append(errs, fmt.Errorf("failed to %s %s: %w", operation, res.Topic, res.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
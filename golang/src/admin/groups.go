@@ -0,0 +1,392 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// GroupListing - краткие сведения о группе консьюмеров
+type GroupListing struct {
+	GroupID string
+	State   string
+	// Simple - группа без протокола координации (только фиксация смещений)
+	Simple bool
+}
+
+// GroupMember - участник группы и назначенные ему партиции
+type GroupMember struct {
+	ConsumerID      string
+	ClientID        string
+	GroupInstanceID string
+	Host            string
+	Partitions      []kafka.TopicPartition
+}
+
+// GroupDescription - состояние группы и ее участники
+type GroupDescription struct {
+	GroupID           string
+	State             string
+	PartitionAssignor string
+	Members           []GroupMember
+}
+
+// PartitionLag - отставание группы в одной партиции
+type PartitionLag struct {
+	Topic     string
+	Partition int32
+	// Committed - зафиксированное смещение группы или -1, если его нет
+	Committed int64
+	// End - верхняя граница партиции (high watermark)
+	End int64
+	// Lag - число сообщений между зафиксированным смещением (или началом партиции,
+	// если смещения нет или оно удалено политикой хранения) и концом партиции
+	Lag int64
+	// ConsumerID - участник, которому назначена партиция; пусто, если не назначена
+	ConsumerID string
+}
+
+// ResetMode - способ вычисления новых смещений группы
+type ResetMode string
+
+const (
+	// ResetEarliest - начало партиции
+	ResetEarliest ResetMode = "earliest"
+	// ResetLatest - конец партиции
+	ResetLatest ResetMode = "latest"
+	// ResetTimestamp - первое сообщение не раньше ResetSpec.Timestamp
+	ResetTimestamp ResetMode = "timestamp"
+	// ResetOffset - конкретное смещение ResetSpec.Offset
+	ResetOffset ResetMode = "offset"
+	// ResetShift - сдвиг текущего смещения на ResetSpec.Shift (может быть отрицательным)
+	ResetShift ResetMode = "shift"
+)
+
+// ResetSpec описывает сброс смещений группы
+type ResetSpec struct {
+	Mode      ResetMode
+	Timestamp time.Time
+	Offset    int64
+	Shift     int64
+}
+
+// OffsetReset - новое смещение группы в партиции
+type OffsetReset struct {
+	Topic     string
+	Partition int32
+	// Current - текущее зафиксированное смещение или -1
+	Current int64
+	Target  int64
+}
+
+// ErrGroupNotEmpty возвращается при сбросе смещений группы с активными участниками
+var ErrGroupNotEmpty = errors.New("consumer group has active members")
+
+// ListGroups возвращает группы консьюмеров кластера
+func (c *Client) ListGroups(ctx context.Context) ([]GroupListing, error) {
+	result, err := c.admin.ListConsumerGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumer groups: %w", err)
+	}
+	if err := errors.Join(result.Errors...); err != nil {
+		return nil, fmt.Errorf("failed to list consumer groups: %w", err)
+	}
+
+	groups := make([]GroupListing, 0, len(result.Valid))
+	for _, g := range result.Valid {
+		groups = append(groups, GroupListing{GroupID: g.GroupID, State: g.State.String(), Simple: g.IsSimpleConsumerGroup})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].GroupID < groups[j].GroupID })
+	return groups, nil
+}
+
+// DescribeGroup возвращает состояние группы, ее участников и их партиции
+func (c *Client) DescribeGroup(ctx context.Context, group string) (GroupDescription, error) {
+	result, err := c.admin.DescribeConsumerGroups(ctx, []string{group})
+	if err != nil {
+		return GroupDescription{}, fmt.Errorf("failed to describe group %s: %w", group, err)
+	}
+	if len(result.ConsumerGroupDescriptions) == 0 {
+		return GroupDescription{}, fmt.Errorf("group %s not found", group)
+	}
+
+	d := result.ConsumerGroupDescriptions[0]
+	if d.Error.Code() != kafka.ErrNoError {
+		return GroupDescription{}, fmt.Errorf("failed to describe group %s: %w", group, d.Error)
+	}
+
+	description := GroupDescription{GroupID: d.GroupID, State: d.State.String(), PartitionAssignor: d.PartitionAssignor}
+	for _, m := range d.Members {
+		description.Members = append(description.Members, GroupMember{
+			ConsumerID:      m.ConsumerID,
+			ClientID:        m.ClientID,
+			GroupInstanceID: m.GroupInstanceID,
+			Host:            m.Host,
+			Partitions:      m.Assignment.TopicPartitions,
+		})
+	}
+	return description, nil
+}
+
+// GroupLag вычисляет отставание группы по каждой партиции, для которой
+// есть зафиксированное смещение или которая назначена участнику группы
+func (c *Client) GroupLag(ctx context.Context, group string) ([]PartitionLag, error) {
+	committed, err := c.committedOffsets(ctx, group, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	description, err := c.DescribeGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[partitionID]string)
+	for _, m := range description.Members {
+		for _, tp := range m.Partitions {
+			id := newPartitionID(tp)
+			owners[id] = m.ConsumerID
+			if _, ok := committed[id]; !ok {
+				committed[id] = -1
+			}
+		}
+	}
+
+	partitions := make([]partitionID, 0, len(committed))
+	for id := range committed {
+		partitions = append(partitions, id)
+	}
+	ends, err := c.listOffsets(ctx, partitions, kafka.LatestOffsetSpec)
+	if err != nil {
+		return nil, err
+	}
+	starts, err := c.listOffsets(ctx, partitions, kafka.EarliestOffsetSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	lags := make([]PartitionLag, 0, len(partitions))
+	for _, id := range partitions {
+		lag := PartitionLag{
+			Topic:      id.topic,
+			Partition:  id.partition,
+			Committed:  committed[id],
+			End:        ends[id],
+			ConsumerID: owners[id],
+		}
+		// Без зафиксированного смещения (или если оно раньше начала партиции после удаления
+		// сообщений по retention или компактизации) отставание считается от начала партиции:
+		// это верхняя оценка - с auto.offset.reset=latest консьюмер начнет с конца
+		lag.Lag = lag.End - max(lag.Committed, starts[id])
+		lags = append(lags, lag)
+	}
+	sort.Slice(lags, func(i, j int) bool {
+		if lags[i].Topic != lags[j].Topic {
+			return lags[i].Topic < lags[j].Topic
+		}
+		return lags[i].Partition < lags[j].Partition
+	})
+	return lags, nil
+}
+
+// ResetOffsets вычисляет новые смещения группы для всех партиций topics и, если dryRun
+// равен false, фиксирует их. Группа не должна иметь активных участников
+func (c *Client) ResetOffsets(ctx context.Context, group string, topics []string, spec ResetSpec, dryRun bool) ([]OffsetReset, error) {
+	partitions, err := c.topicPartitions(topics)
+	if err != nil {
+		return nil, err
+	}
+
+	committed, err := c.committedOffsets(ctx, group, partitions)
+	if err != nil {
+		return nil, err
+	}
+	earliest, err := c.listOffsets(ctx, partitions, kafka.EarliestOffsetSpec)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := c.listOffsets(ctx, partitions, kafka.LatestOffsetSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	var byTime map[partitionID]int64
+	if spec.Mode == ResetTimestamp {
+		byTime, err = c.listOffsets(ctx, partitions, kafka.NewOffsetSpecForTimestamp(spec.Timestamp.UnixMilli()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resets := make([]OffsetReset, 0, len(partitions))
+	for _, id := range partitions {
+		current, ok := committed[id]
+		if !ok {
+			current = -1
+		}
+
+		var target int64
+		switch spec.Mode {
+		case ResetEarliest:
+			target = earliest[id]
+		case ResetLatest:
+			target = latest[id]
+		case ResetTimestamp:
+			// Если сообщений после метки нет, брокер возвращает -1: переходим в конец партиции
+			target = byTime[id]
+			if target < 0 {
+				target = latest[id]
+			}
+		case ResetOffset:
+			target = spec.Offset
+		case ResetShift:
+			if current < 0 {
+				return nil, fmt.Errorf("group %s has no committed offset for %s [%d] to shift", group, id.topic, id.partition)
+			}
+			target = current + spec.Shift
+		default:
+			return nil, fmt.Errorf("unknown reset mode %q", spec.Mode)
+		}
+
+		// Смещение за пределами партиции приводим к ее границам
+		target = max(earliest[id], min(target, latest[id]))
+		resets = append(resets, OffsetReset{Topic: id.topic, Partition: id.partition, Current: current, Target: target})
+	}
+
+	if dryRun {
+		return resets, nil
+	}
+
+	description, err := c.DescribeGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if len(description.Members) > 0 {
+		return nil, fmt.Errorf("%w: %s (%d members, state %s)", ErrGroupNotEmpty, group, len(description.Members), description.State)
+	}
+
+	offsets := make([]kafka.TopicPartition, len(resets))
+	for i, r := range resets {
+		topic := r.Topic
+		offsets[i] = kafka.TopicPartition{Topic: &topic, Partition: r.Partition, Offset: kafka.Offset(r.Target)}
+	}
+	result, err := c.admin.AlterConsumerGroupOffsets(ctx, []kafka.ConsumerGroupTopicPartitions{{Group: group, Partitions: offsets}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset offsets of group %s: %w", group, err)
+	}
+	for _, g := range result.ConsumerGroupsTopicPartitions {
+		for _, tp := range g.Partitions {
+			if tp.Error != nil {
+				return nil, fmt.Errorf("failed to reset offset of %s [%d]: %w", *tp.Topic, tp.Partition, tp.Error)
+			}
+		}
+	}
+
+	c.logger.Info("consumer group offsets reset", slog.String("group", group),
+		slog.String("mode", string(spec.Mode)), slog.Int("partitions", len(resets)))
+	return resets, nil
+}
+
+// partitionID идентифицирует партицию топика; kafka.TopicPartition не подходит
+// в качестве ключа map из-за указателя на имя топика
+type partitionID struct {
+	topic     string
+	partition int32
+}
+
+// newPartitionID создает идентификатор из kafka.TopicPartition
+func newPartitionID(tp kafka.TopicPartition) partitionID {
+	id := partitionID{partition: tp.Partition}
+	if tp.Topic != nil {
+		id.topic = *tp.Topic
+	}
+	return id
+}
+
+// topicPartition преобразует идентификатор в kafka.TopicPartition
+func (id partitionID) topicPartition() kafka.TopicPartition {
+	topic := id.topic
+	return kafka.TopicPartition{Topic: &topic, Partition: id.partition}
+}
+
+// topicPartitions возвращает все партиции указанных топиков
+func (c *Client) topicPartitions(topics []string) ([]partitionID, error) {
+	md, err := c.admin.GetMetadata(nil, true, metadataTimeoutMs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+
+	var partitions []partitionID
+	for _, topic := range topics {
+		tm, ok := md.Topics[topic]
+		if !ok || tm.Error.Code() == kafka.ErrUnknownTopicOrPart {
+			return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+		}
+		for _, p := range tm.Partitions {
+			partitions = append(partitions, partitionID{topic: topic, partition: p.ID})
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].topic != partitions[j].topic {
+			return partitions[i].topic < partitions[j].topic
+		}
+		return partitions[i].partition < partitions[j].partition
+	})
+	return partitions, nil
+}
+
+// committedOffsets возвращает зафиксированные смещения группы;
+// если partitions пуст, возвращаются все партиции со смещениями
+func (c *Client) committedOffsets(ctx context.Context, group string, partitions []partitionID) (map[partitionID]int64, error) {
+	request := kafka.ConsumerGroupTopicPartitions{Group: group}
+	for _, id := range partitions {
+		request.Partitions = append(request.Partitions, id.topicPartition())
+	}
+
+	result, err := c.admin.ListConsumerGroupOffsets(ctx, []kafka.ConsumerGroupTopicPartitions{request})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets of group %s: %w", group, err)
+	}
+
+	committed := make(map[partitionID]int64)
+	for _, g := range result.ConsumerGroupsTopicPartitions {
+		for _, tp := range g.Partitions {
+			if tp.Error != nil {
+				return nil, fmt.Errorf("failed to list offset of %s [%d]: %w", *tp.Topic, tp.Partition, tp.Error)
+			}
+			if tp.Offset >= 0 {
+				committed[newPartitionID(tp)] = int64(tp.Offset)
+			}
+		}
+	}
+	return committed, nil
+}
+
+// listOffsets возвращает смещения партиций по спецификации (начало, конец или время)
+func (c *Client) listOffsets(ctx context.Context, partitions []partitionID, spec kafka.OffsetSpec) (map[partitionID]int64, error) {
+	offsets := make(map[partitionID]int64, len(partitions))
+	if len(partitions) == 0 {
+		return offsets, nil
+	}
+
+	request := make(map[kafka.TopicPartition]kafka.OffsetSpec, len(partitions))
+	for _, id := range partitions {
+		request[id.topicPartition()] = spec
+	}
+
+	result, err := c.admin.ListOffsets(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+	for tp, info := range result.ResultInfos {
+		if info.Error.Code() != kafka.ErrNoError {
+			return nil, fmt.Errorf("failed to list offset of %s [%d]: %w", *tp.Topic, tp.Partition, info.Error)
+		}
+		offsets[newPartitionID(tp)] = int64(info.Offset)
+	}
+	return offsets, nil
+}
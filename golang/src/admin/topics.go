@@ -0,0 +1,257 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// metadataTimeoutMs - время ожидания метаданных кластера
+const metadataTimeoutMs = 10000
+
+// ErrUnreconcilable возвращается, если существующий топик нельзя привести к спецификации
+// без пересоздания: уменьшение числа партиций или изменение фактора репликации
+var ErrUnreconcilable = errors.New("topic cannot be reconciled")
+
+// Action - вид изменения топика
+type Action string
+
+const (
+	// ActionCreate - создание топика
+	ActionCreate Action = "create"
+	// ActionAddPartitions - увеличение числа партиций
+	ActionAddPartitions Action = "add-partitions"
+	// ActionAlterConfig - изменение параметров топика
+	ActionAlterConfig Action = "alter-config"
+)
+
+// Change - изменение, необходимое для приведения топика к спецификации
+type Change struct {
+	Action Action
+	Topic  string
+	// Spec - желаемое состояние топика
+	Spec TopicSpec
+	// Current - текущее состояние; nil, если топик не существует
+	Current *TopicDescription
+	// Config - параметры, значения которых будут установлены (для ActionAlterConfig)
+	Config map[string]string
+}
+
+// String описывает изменение в одну строку для вывода плана
+func (c Change) String() string {
+	switch c.Action {
+	case ActionCreate:
+		return fmt.Sprintf("+ create topic %s (partitions=%d, replication=%d%s)",
+			c.Topic, c.Spec.Partitions, c.Spec.ReplicationFactor, formatConfigs(c.Spec.Configs(), nil))
+	case ActionAddPartitions:
+		return fmt.Sprintf("~ add partitions to %s: %d -> %d", c.Topic, c.Current.Partitions, c.Spec.Partitions)
+	case ActionAlterConfig:
+		return fmt.Sprintf("~ alter config of %s:%s", c.Topic, formatConfigs(c.Config, c.Current.Config))
+	default:
+		return fmt.Sprintf("? %s %s", c.Action, c.Topic)
+	}
+}
+
+// formatConfigs форматирует параметры в порядке имен; если задан old, показывает прежние значения
+func formatConfigs(configs map[string]string, old map[string]string) string {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		if old == nil {
+			fmt.Fprintf(&b, ", %s=%s", name, configs[name])
+			continue
+		}
+		previous, ok := old[name]
+		if !ok {
+			previous = "(default)"
+		}
+		fmt.Fprintf(&b, " %s %s -> %s;", name, previous, configs[name])
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
+
+// ListTopics возвращает имена существующих топиков, кроме служебных
+func (c *Client) ListTopics() ([]string, error) {
+	md, err := c.admin.GetMetadata(nil, true, metadataTimeoutMs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+
+	topics := make([]string, 0, len(md.Topics))
+	for name := range md.Topics {
+		if !strings.HasPrefix(name, "__") {
+			topics = append(topics, name)
+		}
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
+// PlanTopics сравнивает спецификации с текущим состоянием кластера и возвращает
+// необходимые изменения. Если какой-то топик нельзя привести к спецификации,
+// возвращается ошибка ErrUnreconcilable вместе с изменениями для остальных топиков
+func (c *Client) PlanTopics(ctx context.Context, specs []TopicSpec) ([]Change, error) {
+	existing, err := c.ListTopics()
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		exists[name] = true
+	}
+
+	var names []string
+	for _, spec := range specs {
+		if exists[spec.Name] {
+			names = append(names, spec.Name)
+		}
+	}
+
+	current := make(map[string]*TopicDescription)
+	if len(names) > 0 {
+		descriptions, err := c.DescribeTopics(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		for i := range descriptions {
+			current[descriptions[i].Name] = &descriptions[i]
+		}
+	}
+
+	var changes []Change
+	var errs []error
+	for _, spec := range specs {
+		td, ok := current[spec.Name]
+		if !ok {
+			changes = append(changes, Change{Action: ActionCreate, Topic: spec.Name, Spec: spec})
+			continue
+		}
+
+		if spec.ReplicationFactor > 0 && spec.ReplicationFactor != td.ReplicationFactor {
+			errs = append(errs, fmt.Errorf("%w: %s has replication factor %d, want %d",
+				ErrUnreconcilable, spec.Name, td.ReplicationFactor, spec.ReplicationFactor))
+		}
+		switch {
+		case spec.Partitions > td.Partitions:
+			changes = append(changes, Change{Action: ActionAddPartitions, Topic: spec.Name, Spec: spec, Current: td})
+		case spec.Partitions > 0 && spec.Partitions < td.Partitions:
+			errs = append(errs, fmt.Errorf("%w: %s has %d partitions, want %d",
+				ErrUnreconcilable, spec.Name, td.Partitions, spec.Partitions))
+		}
+
+		altered := make(map[string]string)
+		for name, value := range spec.Configs() {
+			if td.Config[name] != value {
+				altered[name] = value
+			}
+		}
+		if len(altered) > 0 {
+			changes = append(changes, Change{Action: ActionAlterConfig, Topic: spec.Name, Spec: spec, Current: td, Config: altered})
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+// ApplyTopics применяет изменения, полученные из PlanTopics
+func (c *Client) ApplyTopics(ctx context.Context, changes []Change) error {
+	for _, change := range changes {
+		var err error
+		switch change.Action {
+		case ActionCreate:
+			err = c.CreateTopics(ctx, []TopicSpec{change.Spec})
+			// Топик мог быть создан параллельно другим экземпляром сервиса
+			var kafkaErr kafka.Error
+			if errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrTopicAlreadyExists {
+				c.logger.Info("topic already exists", slog.String(kafkalib.LogKeyTopic, change.Topic))
+				err = nil
+			}
+		case ActionAddPartitions:
+			err = c.CreatePartitions(ctx, change.Topic, change.Spec.Partitions)
+		case ActionAlterConfig:
+			err = c.AlterConfigs(ctx, change.Topic, change.Config)
+		default:
+			err = fmt.Errorf("unknown action %q for topic %s", change.Action, change.Topic)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureTopics идемпотентно приводит топики к спецификациям: создает отсутствующие,
+// добавляет партиции и обновляет параметры существующих. Ничего не изменяет,
+// если хотя бы один топик нельзя привести к спецификации
+func (c *Client) EnsureTopics(ctx context.Context, specs []TopicSpec) ([]Change, error) {
+	changes, err := c.PlanTopics(ctx, specs)
+	if err != nil {
+		return changes, err
+	}
+	if err := c.ApplyTopics(ctx, changes); err != nil {
+		return changes, err
+	}
+	return changes, nil
+}
+
+// PartitionOffsets - границы партиции топика
+type PartitionOffsets struct {
+	Partition int32
+	// Earliest - смещение первого доступного сообщения
+	Earliest int64
+	// Latest - смещение, которое получит следующее сообщение (high watermark)
+	Latest int64
+}
+
+// PartitionOffsets возвращает границы всех партиций топика
+func (c *Client) PartitionOffsets(ctx context.Context, topic string) ([]PartitionOffsets, error) {
+	partitions, err := c.topicPartitions([]string{topic})
+	if err != nil {
+		return nil, err
+	}
+	earliest, err := c.listOffsets(ctx, partitions, kafka.EarliestOffsetSpec)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := c.listOffsets(ctx, partitions, kafka.LatestOffsetSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make([]PartitionOffsets, len(partitions))
+	for i, id := range partitions {
+		offsets[i] = PartitionOffsets{Partition: id.partition, Earliest: earliest[id], Latest: latest[id]}
+	}
+	return offsets, nil
+}
+
+// OffsetsForTime возвращает для каждой партиции топика смещение первого сообщения
+// с временной меткой не раньше t или -1, если таких сообщений нет
+func (c *Client) OffsetsForTime(ctx context.Context, topic string, t time.Time) (map[int32]int64, error) {
+	partitions, err := c.topicPartitions([]string{topic})
+	if err != nil {
+		return nil, err
+	}
+	byTime, err := c.listOffsets(ctx, partitions, kafka.NewOffsetSpecForTimestamp(t.UnixMilli()))
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int32]int64, len(byTime))
+	for id, offset := range byTime {
+		offsets[id.partition] = offset
+	}
+	return offsets, nil
+}
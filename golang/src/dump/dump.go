@@ -0,0 +1,374 @@
+// Package dump выгружает сообщения топика в файл и загружает их обратно.
+//
+// Поддерживаются два формата. JSON lines - одна запись на строку с полями topic,
+// partition, offset, timestamp (RFC 3339), key и value (base64, null - отсутствует)
+// и headers (список объектов key/value). Двоичный формат начинается с сигнатуры
+// "KDUMP", байта версии (1) и имени топика, за которыми следуют записи:
+//
+//	varint partition
+//	varint offset
+//	varint timestamp  (миллисекунды Unix, -1 - нет метки)
+//	bytes  key
+//	bytes  value
+//	varint число заголовков, затем для каждого bytes key, bytes value
+//
+// где bytes - varint длина и данные, длина -1 означает отсутствующее значение (null).
+// Все varint записаны в формате encoding/binary со знаком (zigzag)
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/kafka-examples/golang/src/admin"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+const (
+	// dumpGroup - группа консьюмера выгрузки; партиции назначаются вручную,
+	// а смещения не фиксируются, поэтому выгрузка не изменяет позиции группы
+	dumpGroup = "kafka-dump"
+	// idleTimeout - если сообщений нет так долго, оставшиеся партиции считаются прочитанными
+	// (диапазон может заканчиваться маркерами транзакций, которые консьюмер не получает)
+	idleTimeout = 10 * time.Second
+	// maxInFlight - максимальное число отправленных, но не доставленных сообщений при загрузке
+	maxInFlight = 10000
+)
+
+// Range ограничивает выгружаемые сообщения
+type Range struct {
+	// Partitions - выгружаемые партиции; пусто - все
+	Partitions []int32
+	// StartOffset - смещение первого сообщения; 0 - с начала партиции
+	StartOffset int64
+	// EndOffset - смещение, на котором выгрузка останавливается (не включая); 0 - до конца партиции
+	EndOffset int64
+	// Since и Until - границы временных меток (включительно); нулевое значение - без ограничения
+	Since time.Time
+	Until time.Time
+}
+
+// Dump выгружает сообщения топика из диапазона r в w и возвращает их число.
+// Выгрузка заканчивается на конце партиций в момент запуска
+func Dump(ctx context.Context, topic string, r Range, w Writer, config map[string]string,
+	logger *slog.Logger, opts ...kafkalib.Option) (int, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	ends, partitions, err := dumpPartitions(ctx, topic, r, config, logger, opts)
+	if err != nil {
+		return 0, err
+	}
+	if len(partitions) == 0 {
+		return 0, w.Flush()
+	}
+
+	consumerConfig := make(map[string]string, len(config)+1)
+	for k, v := range config {
+		consumerConfig[k] = v
+	}
+	consumerConfig["group.id"] = dumpGroup
+
+	consumer, err := kafkalib.NewConsumer(nil, consumerConfig, logger,
+		append(append([]kafkalib.Option(nil), opts...), kafkalib.WithAssignment(partitions), kafkalib.WithoutOffsetCommit())...)
+	if err != nil {
+		return 0, err
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := newIdleTimer(idleTimeout, cancel)
+	defer idle.stop()
+
+	count := 0
+	var writeErr error
+	err = consumer.Run(ctx, func(_ context.Context, msg *kafka.Message) error {
+		idle.reset()
+		p := msg.TopicPartition.Partition
+		offset := int64(msg.TopicPartition.Offset)
+		if offset >= ends[p] {
+			return nil
+		}
+
+		if r.Until.IsZero() || !msg.Timestamp.After(r.Until) {
+			// Ошибка записи не исправится повторной обработкой: выгрузка прерывается
+			if err := w.Write(record(msg)); err != nil {
+				writeErr = fmt.Errorf("failed to write record: %w", err)
+				return kafkalib.ErrStopConsuming
+			}
+			count++
+		}
+
+		if offset+1 >= ends[p] {
+			delete(ends, p)
+		}
+		if len(ends) == 0 {
+			return kafkalib.ErrStopConsuming
+		}
+		return nil
+	})
+
+	if writeErr != nil {
+		return count, writeErr
+	}
+	if errors.Is(err, context.Canceled) && idle.expired() {
+		logger.Warn("no messages received before the end of range", slog.Any("partitions", ends))
+		err = nil
+	}
+	if err != nil {
+		return count, err
+	}
+	return count, w.Flush()
+}
+
+// dumpPartitions вычисляет для каждой партиции диапазона смещение начала чтения
+// и смещение, на котором чтение заканчивается; пустые партиции пропускаются
+func dumpPartitions(ctx context.Context, topic string, r Range, config map[string]string,
+	logger *slog.Logger, opts []kafkalib.Option) (map[int32]int64, []kafka.TopicPartition, error) {
+	client, err := admin.NewClient(config, logger, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.Close()
+
+	offsets, err := client.PartitionOffsets(ctx, topic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var byTime map[int32]int64
+	if !r.Since.IsZero() {
+		if byTime, err = client.OffsetsForTime(ctx, topic, r.Since); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, p := range r.Partitions {
+		if p < 0 || int(p) >= len(offsets) {
+			return nil, nil, fmt.Errorf("topic %s has no partition %d", topic, p)
+		}
+	}
+
+	ends := make(map[int32]int64)
+	var partitions []kafka.TopicPartition
+	for _, po := range offsets {
+		if len(r.Partitions) > 0 && !slices.Contains(r.Partitions, po.Partition) {
+			continue
+		}
+
+		start, end := max(po.Earliest, r.StartOffset), po.Latest
+		if r.EndOffset > 0 {
+			end = min(end, r.EndOffset)
+		}
+		if byTime != nil {
+			if byTime[po.Partition] < 0 {
+				continue
+			}
+			start = max(start, byTime[po.Partition])
+		}
+		if start >= end {
+			continue
+		}
+
+		ends[po.Partition] = end
+		partitions = append(partitions, kafka.TopicPartition{Topic: &topic, Partition: po.Partition, Offset: kafka.Offset(start)})
+	}
+	return ends, partitions, nil
+}
+
+// record преобразует сообщение в запись выгрузки
+func record(msg *kafka.Message) Record {
+	r := Record{
+		Topic:     *msg.TopicPartition.Topic,
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Key:       msg.Key,
+		Value:     msg.Value,
+	}
+	if msg.TimestampType != kafka.TimestampNotAvailable {
+		r.Timestamp = msg.Timestamp
+	}
+	for _, h := range msg.Headers {
+		r.Headers = append(r.Headers, Header{Key: h.Key, Value: h.Value})
+	}
+	return r
+}
+
+// RestoreOptions - параметры загрузки
+type RestoreOptions struct {
+	// Partition выбирает партицию целевого топика; nil - исходная партиция записи.
+	// kafka.PartitionAny передает выбор продюсеру
+	Partition func(Record) int32
+	// Key задает ключ сообщения; nil - исходный ключ
+	Key func(Record) []byte
+	// KeepTimestamp сохраняет исходные временные метки сообщений
+	KeepTimestamp bool
+}
+
+// AnyPartition передает выбор партиции продюсеру (по ключу или Partitioner)
+func AnyPartition(Record) int32 {
+	return kafka.PartitionAny
+}
+
+// FixedPartition направляет все сообщения в одну партицию
+func FixedPartition(partition int32) func(Record) int32 {
+	return func(Record) int32 { return partition }
+}
+
+// KeyFromHeader использует в качестве ключа значение заголовка name;
+// если заголовка нет, ключ не изменяется
+func KeyFromHeader(name string) func(Record) []byte {
+	return func(rec Record) []byte {
+		for _, h := range rec.Headers {
+			if h.Key == name {
+				return h.Value
+			}
+		}
+		return rec.Key
+	}
+}
+
+// KeyFromField использует в качестве ключа поле name JSON-значения: строки без кавычек,
+// остальные типы в виде JSON. Если значение не JSON-объект или поля нет, ключ не изменяется
+func KeyFromField(name string) func(Record) []byte {
+	return func(rec Record) []byte {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Value, &fields); err != nil {
+			return rec.Key
+		}
+		field, ok := fields[name]
+		if !ok {
+			return rec.Key
+		}
+		var s string
+		if err := json.Unmarshal(field, &s); err == nil {
+			return []byte(s)
+		}
+		return field
+	}
+}
+
+// Restore загружает записи из r в топик producer и возвращает число доставленных сообщений
+func Restore(ctx context.Context, producer *kafkalib.Producer, r Reader, options RestoreOptions) (int, error) {
+	deliveries := make(chan kafka.Event, maxInFlight)
+	inFlight, delivered := 0, 0
+	var errs []error
+
+	// await дожидается одного отчета о доставке
+	await := func() error {
+		select {
+		case ev := <-deliveries:
+			inFlight--
+			if msg, ok := ev.(*kafka.Message); ok {
+				if msg.TopicPartition.Error != nil {
+					errs = append(errs, msg.TopicPartition.Error)
+				} else {
+					delivered++
+				}
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return delivered, err
+		}
+
+		message := restoreMessage(rec, options)
+		if inFlight == maxInFlight {
+			if err := await(); err != nil {
+				return delivered, err
+			}
+		}
+		if err := producer.SendMessage(ctx, message, deliveries); err != nil {
+			return delivered, err
+		}
+		inFlight++
+	}
+
+	for inFlight > 0 {
+		if err := await(); err != nil {
+			return delivered, err
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return delivered, fmt.Errorf("failed to deliver %d messages: %w", len(errs), err)
+	}
+	return delivered, nil
+}
+
+// restoreMessage создает сообщение для загрузки записи
+func restoreMessage(rec Record, options RestoreOptions) *kafka.Message {
+	message := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Partition: rec.Partition},
+		Key:            rec.Key,
+		Value:          rec.Value,
+	}
+	if options.Partition != nil {
+		message.TopicPartition.Partition = options.Partition(rec)
+	}
+	if options.Key != nil {
+		message.Key = options.Key(rec)
+	}
+	if options.KeepTimestamp {
+		message.Timestamp = rec.Timestamp
+	}
+	for _, h := range rec.Headers {
+		message.Headers = append(message.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+	}
+	return message
+}
+
+// idleTimer вызывает onIdle, если reset не вызывался дольше timeout
+type idleTimer struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	timeout time.Duration
+	fired   bool
+}
+
+// newIdleTimer запускает таймер простоя
+func newIdleTimer(timeout time.Duration, onIdle func()) *idleTimer {
+	t := &idleTimer{timeout: timeout}
+	t.timer = time.AfterFunc(timeout, func() {
+		t.mu.Lock()
+		t.fired = true
+		t.mu.Unlock()
+		onIdle()
+	})
+	return t
+}
+
+// reset откладывает срабатывание таймера
+func (t *idleTimer) reset() {
+	t.timer.Reset(t.timeout)
+}
+
+// expired сообщает, что таймер сработал
+func (t *idleTimer) expired() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fired
+}
+
+// stop останавливает таймер
+func (t *idleTimer) stop() {
+	t.timer.Stop()
+}
@@ -0,0 +1,266 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format - формат файла выгрузки
+type Format string
+
+const (
+	// FormatJSON - JSON lines: одна запись на строку, ключ, значение и заголовки в base64
+	FormatJSON Format = "json"
+	// FormatBinary - компактный двоичный формат (см. описание пакета)
+	FormatBinary Format = "binary"
+)
+
+// binaryMagic - сигнатура двоичного формата, за ней следует номер версии
+var binaryMagic = []byte("KDUMP")
+
+const (
+	binaryVersion = 1
+	// maxFieldSize - максимальный размер поля двоичной записи; защищает от поврежденных файлов
+	maxFieldSize = 64 * 1024 * 1024
+)
+
+// Header - заголовок сообщения
+type Header struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Record - сообщение топика в файле выгрузки. Отсутствующие ключ и значение
+// (nil) отличаются от пустых
+type Record struct {
+	Topic     string    `json:"topic"`
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Timestamp time.Time `json:"timestamp"`
+	Key       []byte    `json:"key"`
+	Value     []byte    `json:"value"`
+	Headers   []Header  `json:"headers,omitempty"`
+}
+
+// Writer записывает сообщения в файл выгрузки
+type Writer interface {
+	Write(record Record) error
+	// Flush записывает буферизованные данные
+	Flush() error
+}
+
+// Reader читает сообщения из файла выгрузки; в конце файла возвращает io.EOF
+type Reader interface {
+	Read() (Record, error)
+}
+
+// NewWriter создает Writer указанного формата; topic записывается в заголовок двоичного файла
+func NewWriter(w io.Writer, format Format, topic string) (Writer, error) {
+	switch format {
+	case FormatJSON:
+		buf := bufio.NewWriter(w)
+		return &jsonWriter{buf: buf, encoder: json.NewEncoder(buf)}, nil
+	case FormatBinary:
+		bw := &binaryWriter{buf: bufio.NewWriter(w)}
+		bw.buf.Write(binaryMagic)
+		bw.buf.WriteByte(binaryVersion)
+		bw.writeBytes([]byte(topic))
+		return bw, nil
+	default:
+		return nil, fmt.Errorf("unknown dump format %q", format)
+	}
+}
+
+// NewReader создает Reader, определяя формат по сигнатуре файла
+func NewReader(r io.Reader) (Reader, error) {
+	buf := bufio.NewReader(r)
+	magic, err := buf.Peek(len(binaryMagic))
+	if err != nil || !bytes.Equal(magic, binaryMagic) {
+		return &jsonReader{decoder: json.NewDecoder(buf)}, nil
+	}
+
+	buf.Discard(len(binaryMagic))
+	version, err := buf.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump header: %w", err)
+	}
+	if version != binaryVersion {
+		return nil, fmt.Errorf("unsupported dump version %d", version)
+	}
+	br := &binaryReader{buf: buf}
+	topic, err := br.readBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump header: %w", err)
+	}
+	br.topic = string(topic)
+	return br, nil
+}
+
+// jsonWriter записывает JSON lines
+type jsonWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *jsonWriter) Write(record Record) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *jsonWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// jsonReader читает JSON lines
+type jsonReader struct {
+	decoder *json.Decoder
+}
+
+func (r *jsonReader) Read() (Record, error) {
+	var record Record
+	if err := r.decoder.Decode(&record); err != nil {
+		if errors.Is(err, io.EOF) {
+			return record, io.EOF
+		}
+		return record, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return record, nil
+}
+
+// binaryWriter записывает двоичный формат
+type binaryWriter struct {
+	buf     *bufio.Writer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (w *binaryWriter) Write(record Record) error {
+	w.writeVarint(int64(record.Partition))
+	w.writeVarint(record.Offset)
+	timestamp := int64(-1)
+	if !record.Timestamp.IsZero() {
+		timestamp = record.Timestamp.UnixMilli()
+	}
+	w.writeVarint(timestamp)
+	w.writeBytes(record.Key)
+	w.writeBytes(record.Value)
+	w.writeVarint(int64(len(record.Headers)))
+	for _, h := range record.Headers {
+		w.writeBytes([]byte(h.Key))
+		w.writeBytes(h.Value)
+	}
+	return nil
+}
+
+func (w *binaryWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// writeVarint записывает число в формате varint
+func (w *binaryWriter) writeVarint(v int64) {
+	n := binary.PutVarint(w.scratch[:], v)
+	w.buf.Write(w.scratch[:n])
+}
+
+// writeBytes записывает длину и содержимое; nil записывается как длина -1
+func (w *binaryWriter) writeBytes(b []byte) {
+	if b == nil {
+		w.writeVarint(-1)
+		return
+	}
+	w.writeVarint(int64(len(b)))
+	w.buf.Write(b)
+}
+
+// binaryReader читает двоичный формат
+type binaryReader struct {
+	buf   *bufio.Reader
+	topic string
+}
+
+func (r *binaryReader) Read() (Record, error) {
+	record := Record{Topic: r.topic}
+
+	partition, err := binary.ReadVarint(r.buf)
+	if err != nil {
+		// Конец файла допустим только на границе записей
+		if errors.Is(err, io.EOF) {
+			return record, io.EOF
+		}
+		return record, fmt.Errorf("failed to read record: %w", err)
+	}
+	record.Partition = int32(partition)
+
+	if err := r.readRecord(&record); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return record, fmt.Errorf("failed to read record: %w", err)
+	}
+	return record, nil
+}
+
+// readRecord читает поля записи, следующие за номером партиции
+func (r *binaryReader) readRecord(record *Record) error {
+	var err error
+	if record.Offset, err = binary.ReadVarint(r.buf); err != nil {
+		return err
+	}
+	timestamp, err := binary.ReadVarint(r.buf)
+	if err != nil {
+		return err
+	}
+	if timestamp >= 0 {
+		record.Timestamp = time.UnixMilli(timestamp)
+	}
+	if record.Key, err = r.readBytes(); err != nil {
+		return err
+	}
+	if record.Value, err = r.readBytes(); err != nil {
+		return err
+	}
+
+	count, err := binary.ReadVarint(r.buf)
+	if err != nil {
+		return err
+	}
+	if count < 0 || count > maxFieldSize {
+		return fmt.Errorf("invalid header count %d", count)
+	}
+	for i := int64(0); i < count; i++ {
+		key, err := r.readBytes()
+		if err != nil {
+			return err
+		}
+		value, err := r.readBytes()
+		if err != nil {
+			return err
+		}
+		record.Headers = append(record.Headers, Header{Key: string(key), Value: value})
+	}
+	return nil
+}
+
+// readBytes читает поле, записанное writeBytes
+func (r *binaryReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadVarint(r.buf)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case n == -1:
+		return nil, nil
+	case n < 0 || n > maxFieldSize:
+		return nil, fmt.Errorf("invalid field size %d", n)
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.buf, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
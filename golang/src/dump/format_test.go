@@ -0,0 +1,160 @@
+package dump
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRecords - записи с пустыми и отсутствующими полями, которые формат должен различать
+var testRecords = []Record{
+	{
+		Topic:     "orders",
+		Partition: 0,
+		Offset:    0,
+		Timestamp: time.UnixMilli(1700000000123),
+		Key:       []byte("key"),
+		Value:     []byte("value"),
+		Headers:   []Header{{Key: "trace", Value: []byte("abc")}, {Key: "empty", Value: []byte{}}, {Key: "null"}},
+	},
+	{
+		Topic:     "orders",
+		Partition: 3,
+		Offset:    1 << 40,
+		Key:       nil,
+		Value:     []byte{},
+	},
+	{
+		Topic:     "orders",
+		Partition: 2147483647,
+		Offset:    42,
+		Timestamp: time.UnixMilli(1),
+		Key:       []byte{},
+		Value:     nil,
+	},
+	{
+		Topic:     "orders",
+		Partition: 1,
+		Offset:    300,
+		Timestamp: time.UnixMilli(1700000000000),
+		Key:       bytes.Repeat([]byte{0xff}, 200),
+		Value:     bytes.Repeat([]byte("v"), 1000),
+	},
+}
+
+// encode записывает testRecords в двоичном формате и возвращает файл
+// и размеры заголовка и каждой записи
+func encode(t *testing.T) ([]byte, []int) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatBinary, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	boundaries := []int{buf.Len()}
+	for _, record := range testRecords {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		boundaries = append(boundaries, buf.Len())
+	}
+	return buf.Bytes(), boundaries
+}
+
+// readAll читает записи до конца файла или первой ошибки
+func readAll(data []byte) ([]Record, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	data, _ := encode(t)
+
+	records, err := readAll(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(testRecords) {
+		t.Fatalf("read %d records; want %d", len(records), len(testRecords))
+	}
+	for i, got := range records {
+		want := testRecords[i]
+		if !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("record %d: timestamp %v; want %v", i, got.Timestamp, want.Timestamp)
+		}
+		got.Timestamp, want.Timestamp = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("record %d: %+v; want %+v", i, got, want)
+		}
+	}
+}
+
+func TestBinaryTruncated(t *testing.T) {
+	data, boundaries := encode(t)
+
+	// Обрезка на границе записи - корректный конец файла, внутри записи - ошибка
+	for size := boundaries[0]; size < len(data); size++ {
+		complete := 0
+		for complete+1 < len(boundaries) && boundaries[complete+1] <= size {
+			complete++
+		}
+		atBoundary := boundaries[complete] == size
+
+		records, err := readAll(data[:size])
+		if len(records) != complete {
+			t.Fatalf("size %d: read %d records; want %d", size, len(records), complete)
+		}
+		switch {
+		case atBoundary && err != nil:
+			t.Fatalf("size %d: unexpected error %v", size, err)
+		case !atBoundary && !errors.Is(err, io.ErrUnexpectedEOF):
+			t.Fatalf("size %d: error %v; want %v", size, err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+func TestBinaryTruncatedHeader(t *testing.T) {
+	data, boundaries := encode(t)
+
+	for size := len(binaryMagic); size < boundaries[0]; size++ {
+		if _, err := NewReader(bytes.NewReader(data[:size])); err == nil {
+			t.Fatalf("size %d: expected header error", size)
+		}
+	}
+}
+
+func TestBinaryInvalidFieldSize(t *testing.T) {
+	data, boundaries := encode(t)
+
+	// Длина ключа первой записи заменяется на недопустимую: partition, offset
+	// и timestamp первой записи занимают 1, 1 и 6 байт
+	corrupted := append([]byte(nil), data...)
+	corrupted[boundaries[0]+8] = 0x7d // varint -63
+	if _, err := readAll(corrupted); err == nil || !strings.Contains(err.Error(), "invalid field size -63") {
+		t.Fatalf("error %v; want invalid field size", err)
+	}
+}
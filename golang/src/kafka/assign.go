@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// assignTimeout - время ожидания поиска смещений по временной метке при назначении партиций
+const assignTimeout = 10 * time.Second
+
+// WithAssignment включает режим ручного назначения: консьюмер не подписывается на топики
+// и не участвует в перебалансировке группы, а читает только указанные партиции.
+// Offset каждой партиции задает начало чтения: kafka.OffsetBeginning, kafka.OffsetEnd,
+// kafka.OffsetStored (зафиксированная позиция группы) или конкретное смещение
+func WithAssignment(partitions []kafka.TopicPartition) Option {
+	return func(o *options) {
+		o.assignment = partitions
+		o.assignmentTime = time.Time{}
+	}
+}
+
+// WithAssignmentAtTime включает режим ручного назначения, начиная чтение каждой партиции
+// с первого сообщения, временная метка которого не раньше t; Offset партиций не учитывается.
+// Если таких сообщений нет, чтение начнется с конца партиции
+func WithAssignmentAtTime(partitions []kafka.TopicPartition, t time.Time) Option {
+	return func(o *options) {
+		o.assignment = partitions
+		o.assignmentTime = t
+	}
+}
+
+// WithoutOffsetCommit отключает сохранение и фиксацию смещений: консьюмер только читает
+// сообщения и не изменяет позиции группы. Подходит для просмотра и выгрузки топиков,
+// а также для фиксации смещений в транзакции продюсера (SendOffsetsToTransaction)
+func WithoutOffsetCommit() Option {
+	return func(o *options) {
+		o.noCommit = true
+	}
+}
+
+// Assignment возвращает партиции, назначенные консьюмеру
+func (c *Consumer) Assignment() ([]kafka.TopicPartition, error) {
+	partitions, err := c.consumer.Assignment()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment: %w", err)
+	}
+	return partitions, nil
+}
+
+// Position возвращает для каждой назначенной партиции смещение следующего сообщения,
+// которое будет получено. При параллельной обработке позиция может опережать
+// сохраненное смещение на число сообщений в очередях
+func (c *Consumer) Position() ([]kafka.TopicPartition, error) {
+	partitions, err := c.Assignment()
+	if err != nil {
+		return nil, err
+	}
+	positions, err := c.consumer.Position(partitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get position: %w", err)
+	}
+	return positions, nil
+}
+
+// assignPartitions назначает партиции в режиме ручного назначения,
+// предварительно находя смещения по временной метке, если она задана
+func (c *Consumer) assignPartitions(partitions []kafka.TopicPartition, t time.Time) error {
+	if !t.IsZero() {
+		timestamps := make([]kafka.TopicPartition, len(partitions))
+		for i, tp := range partitions {
+			tp.Offset = kafka.Offset(t.UnixMilli())
+			timestamps[i] = tp
+		}
+
+		var err error
+		partitions, err = c.consumer.OffsetsForTimes(timestamps, int(assignTimeout.Milliseconds()))
+		if err != nil {
+			return fmt.Errorf("failed to look up offsets for time %s: %w", t, err)
+		}
+		for _, tp := range partitions {
+			if tp.Error != nil {
+				return fmt.Errorf("failed to look up offset for %s [%d]: %w", *tp.Topic, tp.Partition, tp.Error)
+			}
+		}
+	}
+
+	if err := c.consumer.Assign(partitions); err != nil {
+		return fmt.Errorf("failed to assign partitions: %w", err)
+	}
+
+	c.logger.Info("partitions assigned", slog.Any("partitions", partitions))
+	return nil
+}
+
+// assignedTopics возвращает топики назначенных партиций без повторов
+func assignedTopics(partitions []kafka.TopicPartition) []string {
+	seen := make(map[string]bool)
+	var topics []string
+	for _, tp := range partitions {
+		if tp.Topic != nil && !seen[*tp.Topic] {
+			seen[*tp.Topic] = true
+			topics = append(topics, *tp.Topic)
+		}
+	}
+	return topics
+}
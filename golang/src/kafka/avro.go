@@ -0,0 +1,118 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/riferrei/srclient"
+)
+
+// Формат Confluent: Magic Byte (1 байт) + Schema ID (4 байта) + данные
+const (
+	confluentMagicByte  = 0
+	confluentHeaderSize = 5
+)
+
+// ParseSchemaID извлекает Schema ID и полезную нагрузку из сообщения в формате Confluent
+func ParseSchemaID(data []byte) (int, []byte, error) {
+	if len(data) < confluentHeaderSize {
+		return 0, nil, fmt.Errorf("message too short for Confluent wire format: %d bytes", len(data))
+	}
+	if data[0] != confluentMagicByte {
+		return 0, nil, fmt.Errorf("invalid magic byte: %d", data[0])
+	}
+	return int(binary.BigEndian.Uint32(data[1:confluentHeaderSize])), data[confluentHeaderSize:], nil
+}
+
+// AvroDecoder десериализует Avro-сообщения, получая схемы из Schema Registry
+type AvroDecoder struct {
+	client srclient.ISchemaRegistryClient
+	mu     sync.Mutex
+	codecs map[int]*goavro.Codec
+}
+
+// NewAvroDecoder создает декодер с кэшем схем
+func NewAvroDecoder(client srclient.ISchemaRegistryClient) *AvroDecoder {
+	return &AvroDecoder{
+		client: client,
+		codecs: make(map[int]*goavro.Codec),
+	}
+}
+
+// Decode десериализует Avro-запись в map; подходит для middleware Deserialize
+func (d *AvroDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	native, _, err := d.DecodeNative(data)
+	if err != nil {
+		return nil, err
+	}
+
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("avro value is %T, not a record", native)
+	}
+	return record, nil
+}
+
+// DecodeNative десериализует Avro-значение произвольного типа и возвращает его Schema ID
+func (d *AvroDecoder) DecodeNative(data []byte) (interface{}, int, error) {
+	native, _, schemaID, err := d.decode(data)
+	return native, schemaID, err
+}
+
+// DecodeJSON десериализует Avro-значение в JSON-кодировку Avro и возвращает его Schema ID
+func (d *AvroDecoder) DecodeJSON(data []byte) ([]byte, int, error) {
+	native, codec, schemaID, err := d.decode(data)
+	if err != nil {
+		return nil, schemaID, err
+	}
+
+	textual, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return nil, schemaID, fmt.Errorf("failed to encode avro value as JSON: %w", err)
+	}
+	return textual, schemaID, nil
+}
+
+// decode десериализует Avro-значение и возвращает его вместе с кодеком схемы
+func (d *AvroDecoder) decode(data []byte) (interface{}, *goavro.Codec, int, error) {
+	schemaID, payload, err := ParseSchemaID(data)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	codec, err := d.codec(schemaID)
+	if err != nil {
+		return nil, nil, schemaID, err
+	}
+
+	native, _, err := codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, nil, schemaID, fmt.Errorf("failed to decode avro value: %w", err)
+	}
+	return native, codec, schemaID, nil
+}
+
+// codec возвращает кодек схемы из кэша или из Schema Registry
+func (d *AvroDecoder) codec(schemaID int) (*goavro.Codec, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if codec, ok := d.codecs[schemaID]; ok {
+		return codec, nil
+	}
+
+	schema, err := d.client.GetSchema(schemaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema %d: %w", schemaID, err)
+	}
+
+	codec, err := goavro.NewCodec(schema.Schema())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create avro codec: %w", err)
+	}
+
+	d.codecs[schemaID] = codec
+	return codec, nil
+}
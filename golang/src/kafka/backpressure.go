@@ -0,0 +1,139 @@
+package kafka
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// WithBackpressure включает автоматическую приостановку партиций: когда число полученных,
+// но еще не обработанных сообщений партиции достигает highWater, партиция приостанавливается,
+// а когда снижается до lowWater, чтение возобновляется. Цикл получения при этом не блокируется,
+// поэтому медленный обработчик не приводит к превышению max.poll.interval.ms.
+// Включает обработку партиций отдельными горутинами (WithPartitionWorkers), если она не задана
+func WithBackpressure(highWater int, lowWater int) Option {
+	return func(o *options) {
+		if highWater < 1 {
+			highWater = 1
+		}
+		if lowWater < 0 {
+			lowWater = 0
+		}
+		if lowWater >= highWater {
+			lowWater = highWater - 1
+		}
+		o.highWater = highWater
+		o.lowWater = lowWater
+	}
+}
+
+// Pause приостанавливает получение сообщений указанных партиций до вызова Resume.
+// Приостановка сбрасывается при отзыве партиций
+func (c *Consumer) Pause(partitions []kafka.TopicPartition) error {
+	return c.flow.pause(c.consumer, partitions)
+}
+
+// Resume возобновляет получение сообщений партиций, приостановленных Pause;
+// партиции, приостановленные backpressure, возобновятся после разгрузки очереди
+func (c *Consumer) Resume(partitions []kafka.TopicPartition) error {
+	return c.flow.resume(c.consumer, partitions)
+}
+
+// flowControl учитывает причины приостановки партиций: вызов Pause и backpressure.
+// Партиция читается, только если ни одна из причин не действует
+type flowControl struct {
+	mu        sync.Mutex
+	manual    map[partitionKey]bool
+	throttled map[partitionKey]bool
+}
+
+// pause приостанавливает партиции по вызову Pause
+func (f *flowControl) pause(consumer *kafka.Consumer, partitions []kafka.TopicPartition) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := consumer.Pause(partitions); err != nil {
+		return fmt.Errorf("failed to pause partitions: %w", err)
+	}
+	if f.manual == nil {
+		f.manual = make(map[partitionKey]bool)
+	}
+	for _, tp := range partitions {
+		f.manual[newPartitionKey(tp)] = true
+	}
+	return nil
+}
+
+// resume снимает приостановку Pause с партиций, не удерживаемых backpressure
+func (f *flowControl) resume(consumer *kafka.Consumer, partitions []kafka.TopicPartition) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var resumed []kafka.TopicPartition
+	for _, tp := range partitions {
+		key := newPartitionKey(tp)
+		delete(f.manual, key)
+		if !f.throttled[key] {
+			resumed = append(resumed, tp)
+		}
+	}
+	if len(resumed) == 0 {
+		return nil
+	}
+	if err := consumer.Resume(resumed); err != nil {
+		return fmt.Errorf("failed to resume partitions: %w", err)
+	}
+	return nil
+}
+
+// adjust приостанавливает или возобновляет партицию по числу необработанных сообщений.
+// pending вычисляется под блокировкой, чтобы последний вызов видел актуальную очередь
+func (f *flowControl) adjust(c *Consumer, key partitionKey, pending func() int, highWater int, lowWater int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := pending()
+	switch {
+	case !f.throttled[key] && n >= highWater:
+		if f.throttled == nil {
+			f.throttled = make(map[partitionKey]bool)
+		}
+		f.throttled[key] = true
+		if f.manual[key] {
+			return
+		}
+		c.logger.Debug("partition paused by backpressure",
+			slog.String(LogKeyTopic, key.topic), slog.Int(LogKeyPartition, int(key.partition)), slog.Int("pending", n))
+		if err := c.consumer.Pause([]kafka.TopicPartition{key.topicPartition(kafka.OffsetInvalid)}); err != nil {
+			c.logger.Error("failed to pause partition", slog.String(LogKeyTopic, key.topic),
+				slog.Int(LogKeyPartition, int(key.partition)), slog.Any(LogKeyError, err))
+		}
+
+	case f.throttled[key] && n <= lowWater:
+		delete(f.throttled, key)
+		if f.manual[key] {
+			return
+		}
+		c.logger.Debug("partition resumed by backpressure",
+			slog.String(LogKeyTopic, key.topic), slog.Int(LogKeyPartition, int(key.partition)), slog.Int("pending", n))
+		if err := c.consumer.Resume([]kafka.TopicPartition{key.topicPartition(kafka.OffsetInvalid)}); err != nil {
+			c.logger.Error("failed to resume partition", slog.String(LogKeyTopic, key.topic),
+				slog.Int(LogKeyPartition, int(key.partition)), slog.Any(LogKeyError, err))
+		}
+	}
+}
+
+// forget сбрасывает состояние отозванных партиций: librdkafka не сохраняет
+// приостановку между назначениями
+func (f *flowControl) forget(partitions []kafka.TopicPartition) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, tp := range partitions {
+		key := newPartitionKey(tp)
+		delete(f.manual, key)
+		delete(f.throttled, key)
+	}
+}
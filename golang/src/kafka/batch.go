@@ -0,0 +1,328 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// batchPollInterval - максимальное время ожидания одного события в RunBatch
+const batchPollInterval = 100 * time.Millisecond
+
+// BatchHandler обрабатывает пакет сообщений; ошибка означает, что пакет не обработан
+// и будет получен повторно. *PartialBatchError означает, что обработана только часть пакета.
+// ErrStopConsuming останавливает RunBatch, а смещения всего пакета фиксируются
+type BatchHandler func(ctx context.Context, msgs []*kafka.Message) error
+
+// PartialBatchError возвращается BatchHandler, если обработаны только первые Processed
+// сообщений пакета: их смещения фиксируются, а остальные сообщения будут получены повторно.
+// Err - необязательная причина; без нее остановка не считается ошибкой (например,
+// оставшиеся сообщения еще рано обрабатывать)
+type PartialBatchError struct {
+	Processed int
+	Err       error
+}
+
+func (e *PartialBatchError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("batch partially processed: %d messages", e.Processed)
+	}
+	return fmt.Sprintf("batch partially processed: %d messages: %v", e.Processed, e.Err)
+}
+
+func (e *PartialBatchError) Unwrap() error {
+	return e.Err
+}
+
+// WithPartitionBatches включает накопление пакетов RunBatch отдельно для каждой партиции;
+// по умолчанию пакет собирается из сообщений всех партиций
+func WithPartitionBatches() Option {
+	return func(o *options) {
+		o.partitionBatches = true
+	}
+}
+
+// RunBatch накапливает сообщения в пакеты до maxSize сообщений или до истечения maxWait
+// с момента получения первого сообщения пакета и передает их обработчику.
+// Смещения пакета фиксируются только после успешной обработки; при ошибке партиции пакета
+// возвращаются к его первому сообщению, и получение сообщений приостанавливается на паузу,
+// которая растет с каждой ошибкой подряд от 100 мс до 10 секунд. Работает до отмены ctx
+// или ErrStopConsuming; в последнем случае смещения всего пакета фиксируются
+func (c *Consumer) RunBatch(ctx context.Context, handler BatchHandler, maxSize int, maxWait time.Duration) error {
+	if maxSize < 1 {
+		return fmt.Errorf("batch size must be positive: %d", maxSize)
+	}
+
+	c.batcher = &batcher{
+		consumer:     c,
+		handler:      handler,
+		ctx:          ctx,
+		maxSize:      maxSize,
+		maxWait:      maxWait,
+		perPartition: c.partitionBatches,
+		batches:      make(map[partitionKey]*batch),
+		failures:     make(map[partitionKey]int),
+	}
+	defer func() { c.batcher = nil }()
+
+	done := c.enterLoop()
+	defer done()
+
+	c.running.Store(true)
+	c.logger.Info("batch consumer started", slog.Any("topics", c.topics),
+		slog.Int("max_size", maxSize), slog.Duration("max_wait", maxWait))
+
+	for c.running.Load() && ctx.Err() == nil {
+		msg, err := c.poll(int(c.batcher.pollTimeout().Milliseconds()))
+		if err != nil {
+			c.logger.Error("failed to consume message", slog.Any(LogKeyError, err))
+		}
+		if msg != nil {
+			c.metrics.received(msg, c.highWatermark(msg.TopicPartition))
+			c.batcher.add(msg)
+		}
+		c.batcher.flushExpired()
+		c.batcher.backoff()
+	}
+
+	// Обрабатываем накопленные сообщения, даже если ctx уже отменен,
+	// но не дольше времени остановки
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.shutdownTimeout)
+	defer cancel()
+	c.batcher.ctx = flushCtx
+	c.batcher.flushAll()
+
+	return ctx.Err()
+}
+
+// batch - накапливаемый пакет сообщений
+type batch struct {
+	msgs     []*kafka.Message
+	deadline time.Time
+}
+
+// batcher накапливает сообщения и передает пакеты обработчику
+type batcher struct {
+	consumer     *Consumer
+	handler      BatchHandler
+	ctx          context.Context
+	maxSize      int
+	maxWait      time.Duration
+	perPartition bool
+	batches      map[partitionKey]*batch
+	// failures - число ошибок обработки пакета подряд; retryDelay - пауза перед
+	// следующим получением сообщений после ошибки
+	failures   map[partitionKey]int
+	retryDelay time.Duration
+}
+
+// batchKey возвращает ключ пакета: партицию сообщения или общий ключ
+func (b *batcher) batchKey(msg *kafka.Message) partitionKey {
+	if b.perPartition {
+		return newPartitionKey(msg.TopicPartition)
+	}
+	return partitionKey{}
+}
+
+// add добавляет сообщение в пакет и обрабатывает пакет, если он заполнен
+func (b *batcher) add(msg *kafka.Message) {
+	key := b.batchKey(msg)
+	bt, ok := b.batches[key]
+	if !ok {
+		bt = &batch{deadline: time.Now().Add(b.maxWait)}
+		b.batches[key] = bt
+	}
+
+	bt.msgs = append(bt.msgs, msg)
+	if len(bt.msgs) >= b.maxSize {
+		b.flush(key)
+	}
+}
+
+// pollTimeout возвращает время ожидания до ближайшего истечения пакета
+func (b *batcher) pollTimeout() time.Duration {
+	timeout := batchPollInterval
+	for _, bt := range b.batches {
+		if until := time.Until(bt.deadline); until < timeout {
+			timeout = until
+		}
+	}
+	if timeout < time.Millisecond {
+		timeout = time.Millisecond
+	}
+	return timeout
+}
+
+// flushExpired обрабатывает пакеты, время ожидания которых истекло
+func (b *batcher) flushExpired() {
+	now := time.Now()
+	for key, bt := range b.batches {
+		if !now.Before(bt.deadline) {
+			b.flush(key)
+		}
+	}
+}
+
+// flushAll обрабатывает все накопленные пакеты
+func (b *batcher) flushAll() {
+	for key := range b.batches {
+		b.flush(key)
+	}
+}
+
+// flushPartitions обрабатывает пакеты, содержащие сообщения указанных партиций
+func (b *batcher) flushPartitions(partitions []kafka.TopicPartition) {
+	if !b.perPartition {
+		// Общий пакет может содержать сообщения любой партиции
+		b.flushAll()
+		return
+	}
+	for _, tp := range partitions {
+		if _, ok := b.batches[newPartitionKey(tp)]; ok {
+			b.flush(newPartitionKey(tp))
+		}
+	}
+}
+
+// dropPartitions отбрасывает сообщения потерянных партиций; их получит новый владелец
+func (b *batcher) dropPartitions(partitions []kafka.TopicPartition) {
+	lost := make(map[partitionKey]bool, len(partitions))
+	for _, tp := range partitions {
+		lost[newPartitionKey(tp)] = true
+		delete(b.failures, newPartitionKey(tp))
+	}
+
+	for key, bt := range b.batches {
+		kept := bt.msgs[:0]
+		for _, msg := range bt.msgs {
+			if !lost[newPartitionKey(msg.TopicPartition)] {
+				kept = append(kept, msg)
+			}
+		}
+		if len(kept) == 0 {
+			delete(b.batches, key)
+		} else {
+			bt.msgs = kept
+		}
+	}
+}
+
+// flush передает пакет обработчику и фиксирует смещения при успехе
+// или возвращает партиции к началу пакета при ошибке
+func (b *batcher) flush(key partitionKey) {
+	bt := b.batches[key]
+	delete(b.batches, key)
+	if bt == nil || len(bt.msgs) == 0 {
+		return
+	}
+
+	started := time.Now()
+	b.consumer.currentBatch.Store(&bt.msgs)
+	err := b.handler(b.ctx, bt.msgs)
+	b.consumer.currentBatch.Store(nil)
+	b.consumer.logger.Debug("batch handled",
+		slog.Int("size", len(bt.msgs)), slog.Duration(LogKeyLatency, time.Since(started)))
+
+	var partial *PartialBatchError
+	if errors.As(err, &partial) && partial.Processed < len(bt.msgs) {
+		if partial.Err != nil {
+			b.consumer.logger.Error("batch handling stopped",
+				slog.Int("size", len(bt.msgs)), slog.Int("processed", partial.Processed), slog.Any(LogKeyError, partial.Err))
+		}
+		processed := max(partial.Processed, 0)
+		b.rewind(bt.msgs[processed:])
+		b.commit(bt.msgs[:processed])
+		if partial.Err != nil {
+			b.failed(key)
+		} else {
+			delete(b.failures, key)
+		}
+		return
+	}
+	if err != nil && partial == nil && !errors.Is(err, ErrStopConsuming) {
+		b.consumer.logger.Error("batch handling failed",
+			slog.Int("size", len(bt.msgs)), slog.Any(LogKeyError, err))
+		b.rewind(bt.msgs)
+		b.failed(key)
+		return
+	}
+	delete(b.failures, key)
+	if errors.Is(err, ErrStopConsuming) {
+		b.consumer.running.Store(false)
+	}
+
+	b.commit(bt.msgs)
+}
+
+// failed учитывает ошибку обработки пакета и увеличивает паузу перед повторным получением
+func (b *batcher) failed(key partitionKey) {
+	b.failures[key]++
+	b.retryDelay = max(b.retryDelay, retryDelay(b.failures[key]))
+}
+
+// backoff выдерживает паузу после ошибки обработки пакета, чтобы повторная обработка
+// не превращалась в непрерывный цикл запросов к брокеру и обработчику
+func (b *batcher) backoff() {
+	if b.retryDelay == 0 {
+		return
+	}
+	sleep(b.retryDelay, b.ctx.Done())
+	b.retryDelay = 0
+}
+
+// commit сохраняет и фиксирует позиции после обработанных сообщений
+func (b *batcher) commit(msgs []*kafka.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	// Позиция фиксации - следующее смещение после последнего сообщения партиции в пакете
+	offsets := incrementOffsets(batchPositions(msgs, func(next, current kafka.Offset) bool { return next > current }))
+	for _, tp := range offsets {
+		b.consumer.storeOffset(tp)
+	}
+	b.consumer.commitOffsets(offsets)
+}
+
+// rewind возвращает партиции пакета к его первому сообщению для повторного получения
+func (b *batcher) rewind(msgs []*kafka.Message) {
+	first := batchPositions(msgs, func(next, current kafka.Offset) bool { return next < current })
+	for _, tp := range first {
+		if err := b.consumer.consumer.Seek(tp, 0); err != nil {
+			b.consumer.logger.Error("failed to rewind partition",
+				slog.String(LogKeyTopic, *tp.Topic), slog.Int(LogKeyPartition, int(tp.Partition)),
+				slog.Any(LogKeyOffset, tp.Offset), slog.Any(LogKeyError, err))
+		}
+	}
+}
+
+// batchPositions выбирает для каждой партиции пакета одно смещение по правилу better
+func batchPositions(msgs []*kafka.Message, better func(next, current kafka.Offset) bool) []kafka.TopicPartition {
+	positions := make(map[partitionKey]kafka.Offset)
+	for _, msg := range msgs {
+		key := newPartitionKey(msg.TopicPartition)
+		if current, ok := positions[key]; !ok || better(msg.TopicPartition.Offset, current) {
+			positions[key] = msg.TopicPartition.Offset
+		}
+	}
+
+	result := make([]kafka.TopicPartition, 0, len(positions))
+	for key, offset := range positions {
+		result = append(result, key.topicPartition(offset))
+	}
+	return result
+}
+
+// incrementOffsets возвращает позиции, следующие за указанными смещениями
+func incrementOffsets(offsets []kafka.TopicPartition) []kafka.TopicPartition {
+	result := make([]kafka.TopicPartition, len(offsets))
+	for i, tp := range offsets {
+		tp.Offset++
+		result[i] = tp
+	}
+	return result
+}
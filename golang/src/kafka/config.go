@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Option настраивает дополнительные параметры Producer и Consumer
+type Option func(*options)
+
+// options - общие настройки клиентов, заполняемые через Option
+type options struct {
+	security       *SecurityConfig
+	librdkafkaLogs bool
+	metrics        *Metrics
+	tracing        *tracing
+
+	// Параметры, применимые только к Producer
+	partitioner  Partitioner
+	delayBuckets DelayBuckets
+
+	// Параметры, применимые только к Consumer
+	workerQueueSize      int
+	workersPerPartition  int
+	partitionBatches     bool
+	cooperativeRebalance bool
+	staticMembership     bool
+	groupInstanceID      string
+	shutdownTimeout      time.Duration
+	highWater            int
+	lowWater             int
+	assignment           []kafka.TopicPartition
+	assignmentTime       time.Time
+	noCommit             bool
+}
+
+// WithSecurity задает параметры безопасности подключения к брокерам
+func WithSecurity(security *SecurityConfig) Option {
+	return func(o *options) {
+		o.security = security
+	}
+}
+
+// newOptions применяет переданные опции к настройкам по умолчанию
+func newOptions(opts []Option) *options {
+	o := &options{shutdownTimeout: defaultShutdownTimeout, delayBuckets: DefaultDelayBuckets}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// buildConfigMap объединяет конфигурацию по умолчанию, пользовательскую конфигурацию
+// и параметры безопасности в kafka.ConfigMap
+func buildConfigMap(defaultConfig map[string]string, config map[string]string, o *options) (kafka.ConfigMap, error) {
+	// Объединяем с пользовательской конфигурацией
+	for k, v := range config {
+		defaultConfig[k] = v
+	}
+
+	// Добавляем параметры безопасности
+	if o.security != nil {
+		securityConfig, err := o.security.ConfigMap()
+		if err != nil {
+			return nil, fmt.Errorf("invalid security config: %w", err)
+		}
+		for k, v := range securityConfig {
+			defaultConfig[k] = v
+		}
+	}
+
+	// Преобразуем map в kafka.ConfigMap
+	configMap := kafka.ConfigMap{}
+	for k, v := range defaultConfig {
+		configMap[k] = v
+	}
+
+	// Включаем пересылку логов librdkafka в канал Logs()
+	if o.librdkafkaLogs {
+		configMap["go.logs.channel.enable"] = true
+	}
+
+	return configMap, nil
+}
+
+// NewAdminClient создает kafka.AdminClient с адресом брокеров по умолчанию
+// и параметрами безопасности из опций, как у Producer и Consumer.
+// Остальные опции к административному клиенту не применяются
+func NewAdminClient(config map[string]string, opts ...Option) (*kafka.AdminClient, error) {
+	o := newOptions(opts)
+
+	defaultConfig := map[string]string{
+		"bootstrap.servers": "kafka:29092",
+	}
+
+	configMap, err := buildConfigMap(defaultConfig, config, o)
+	if err != nil {
+		return nil, err
+	}
+	// У AdminClient нет канала Logs(), поэтому логи librdkafka не перенаправляются
+	delete(configMap, "go.logs.channel.enable")
+
+	a, err := kafka.NewAdminClient(&configMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin client: %w", err)
+	}
+
+	// Административные операции кратковременны, поэтому достаточно начального OAuth-токена
+	if tokenProvider := o.security.tokenProvider(); tokenProvider != nil {
+		if err := refreshOAuthBearerToken(a, tokenProvider); err != nil {
+			a.Close()
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
@@ -2,26 +2,81 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
+const (
+	// retryInitial и retryMax - начальная и максимальная пауза перед повторной обработкой
+	// сообщения или пакета после ошибки; пауза удваивается с каждой ошибкой подряд
+	retryInitial = 100 * time.Millisecond
+	retryMax     = 10 * time.Second
+)
+
 // MessageHandler - тип функции для обработки сообщений
 type MessageHandler func(*kafka.Message) bool
 
 // Consumer представляет Kafka консьюмера
 type Consumer struct {
-	consumer *kafka.Consumer
-	topics   []string
-	logger   *log.Logger
-	running  bool
+	consumer      *kafka.Consumer
+	topics        []string
+	logger        *slog.Logger
+	running       atomic.Bool
+	tokenProvider TokenProvider
+	metrics       *Metrics
+	tracing       *tracing
+	group         string
+	middlewares   []Middleware
+
+	// Параллельная обработка (WithPartitionWorkers, WithKeyOrderedWorkers)
+	workerQueueSize     int
+	workersPerPartition int
+	// workers читается и при Shutdown, поэтому хранится атомарно
+	workers atomic.Pointer[workerPool]
+	// failures - число ошибок обработки подряд по партициям при последовательной обработке
+	failures map[partitionKey]int
+	// Приостановка партиций (Pause, WithBackpressure)
+	flow      flowControl
+	highWater int
+	lowWater  int
+	// Обработчики событий перебалансировки
+	onAssigned []RebalanceHook
+	onRevoked  []RebalanceHook
+	onLost     []RebalanceHook
+
+	// Пакетная обработка (RunBatch)
+	partitionBatches bool
+	batcher          *batcher
+
+	// Остановка (Shutdown)
+	shutdownTimeout time.Duration
+	// current - сообщение, обрабатываемое без пула обработчиков; currentBatch - пакет RunBatch
+	current      atomic.Pointer[kafka.Message]
+	currentBatch atomic.Pointer[[]*kafka.Message]
+	// pollMu исключает Poll во время закрытия соединения
+	pollMu sync.Mutex
+	// abandoned - соединение закрыто до завершения обработки; смещения больше не сохраняются
+	abandoned atomic.Bool
+	// noCommit - смещения не сохраняются и не фиксируются (WithoutOffsetCommit)
+	noCommit bool
+	loopMu   sync.Mutex
+	// loopDone закрывается при завершении цикла обработки
+	loopDone   chan struct{}
+	unfinished []UnfinishedPartition
 }
 
-// NewConsumer создает новый экземпляр консьюмера Kafka
-func NewConsumer(topics []string, config map[string]string, logger *log.Logger) (*Consumer, error) {
+// NewConsumer создает новый экземпляр консьюмера Kafka, подписанный на topics.
+// С опцией WithAssignment консьюмер читает указанные партиции без подписки, topics можно не задавать.
+// Если logger равен nil, используется slog.Default()
+func NewConsumer(topics []string, config map[string]string, logger *slog.Logger, opts ...Option) (*Consumer, error) {
+	o := newOptions(opts)
+
 	// Создаем базовую конфигурацию
 	defaultConfig := map[string]string{
 		"bootstrap.servers":  "kafka:29092",
@@ -30,15 +85,31 @@ func NewConsumer(topics []string, config map[string]string, logger *log.Logger)
 		"enable.auto.commit": "true",
 	}
 
-	// Объединяем с пользовательской конфигурацией
-	for k, v := range config {
-		defaultConfig[k] = v
+	// Объединяем с пользовательской конфигурацией и параметрами безопасности
+	configMap, err := buildConfigMap(defaultConfig, config, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Протокол cooperative-sticky с инкрементальным назначением партиций
+	if o.cooperativeRebalance {
+		configMap["partition.assignment.strategy"] = "cooperative-sticky"
 	}
 
-	// Преобразуем map в kafka.ConfigMap
-	configMap := kafka.ConfigMap{}
-	for k, v := range defaultConfig {
-		configMap[k] = v
+	// Статическое членство: перезапуск участника не вызывает перебалансировку
+	if o.staticMembership {
+		instanceID, err := o.instanceID()
+		if err != nil {
+			return nil, err
+		}
+		configMap["group.instance.id"] = instanceID
+	}
+
+	// Смещение сохраняется библиотекой только после обработки сообщения или пакета,
+	// а не в момент получения
+	configMap["enable.auto.offset.store"] = "false"
+	if o.noCommit {
+		configMap["enable.auto.commit"] = "false"
 	}
 
 	// Создаем консьюмера
@@ -47,70 +118,304 @@ func NewConsumer(topics []string, config map[string]string, logger *log.Logger)
 		return nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
 
+	// Все записи консьюмера содержат идентификатор группы
+	logger = loggerOrDefault(logger).With(slog.String(LogKeyGroup, configMap["group.id"].(string)))
+	if o.librdkafkaLogs {
+		go forwardLibrdkafkaLogs(c.Logs(), logger)
+	}
+
+	// Передаем начальный OAuth-токен, последующие обновления запрашивает librdkafka
+	tokenProvider := o.security.tokenProvider()
+	if tokenProvider != nil {
+		if err := refreshOAuthBearerToken(c, tokenProvider); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	// Backpressure работает поверх очередей обработчиков партиций; очередь вмещает
+	// highWater сообщений, чтобы цикл получения не блокировался до приостановки партиции
+	if o.highWater > 0 {
+		if o.workersPerPartition == 0 {
+			o.workersPerPartition = 1
+		}
+		if o.workerQueueSize < o.highWater {
+			o.workerQueueSize = o.highWater
+		}
+	}
+
+	consumer := &Consumer{
+		consumer:            c,
+		topics:              topics,
+		logger:              logger,
+		tokenProvider:       tokenProvider,
+		metrics:             o.metrics,
+		tracing:             o.tracing,
+		group:               configMap["group.id"].(string),
+		workerQueueSize:     o.workerQueueSize,
+		workersPerPartition: o.workersPerPartition,
+		partitionBatches:    o.partitionBatches,
+		shutdownTimeout:     o.shutdownTimeout,
+		highWater:           o.highWater,
+		lowWater:            o.lowWater,
+		noCommit:            o.noCommit,
+	}
+
+	// В режиме ручного назначения читаем только указанные партиции
+	if o.assignment != nil {
+		if len(topics) == 0 {
+			consumer.topics = assignedTopics(o.assignment)
+		}
+		if err := consumer.assignPartitions(o.assignment, o.assignmentTime); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return consumer, nil
+	}
+
 	// Подписываемся на топики
-	if err := c.SubscribeTopics(topics, nil); err != nil {
+	if err := c.SubscribeTopics(topics, consumer.rebalance); err != nil {
 		c.Close()
 		return nil, fmt.Errorf("failed to subscribe to topics: %w", err)
 	}
 
-	return &Consumer{
-		consumer: c,
-		topics:   topics,
-		logger:   logger,
-		running:  false,
-	}, nil
+	return consumer, nil
 }
 
-// Consume получает сообщение из Kafka с таймаутом
+// Consume получает сообщение из Kafka с таймаутом и передает его обработчику
 func (c *Consumer) Consume(timeoutMs int, handler MessageHandler) error {
-	// Получаем сообщение с указанным таймаутом
-	msg, err := c.consumer.ReadMessage(time.Duration(timeoutMs) * time.Millisecond)
+	var h Handler
+	if handler != nil {
+		h = handler.Handler()
+	}
+	return c.consume(context.Background(), timeoutMs, c.wrap(h))
+}
+
+// consume получает одно сообщение и передает его обработчику с middleware
+// или обработчику партиции, если включена параллельная обработка
+func (c *Consumer) consume(ctx context.Context, timeoutMs int, handler Handler) error {
+	msg, err := c.poll(timeoutMs)
+	if err != nil || msg == nil {
+		return err
+	}
+
+	c.metrics.received(msg, c.highWatermark(msg.TopicPartition))
+
+	if workers := c.workers.Load(); workers != nil {
+		workers.dispatch(msg)
+		return nil
+	}
+
+	c.current.Store(msg)
+	err = handler(ctx, msg)
+	c.current.Store(nil)
+	if err != nil && !errors.Is(err, ErrStopConsuming) {
+		// Смещение не сохраняется: сообщение будет получено и обработано повторно
+		c.retry(ctx, msg)
+		return fmt.Errorf("failed to handle message: %w", err)
+	}
+	delete(c.failures, newPartitionKey(msg.TopicPartition))
+
+	tp := msg.TopicPartition
+	tp.Offset++
+	c.storeOffset(tp)
 	if err != nil {
-		// Проверяем, является ли ошибка таймаутом
-		if err.(kafka.Error).Code() == kafka.ErrTimedOut {
-			return nil // Таймаут - не ошибка
+		c.running.Store(false)
+	}
+	return nil
+}
+
+// retry возвращает партицию к сообщению, обработка которого завершилась ошибкой,
+// и выдерживает паузу, растущую с каждой ошибкой подряд
+func (c *Consumer) retry(ctx context.Context, msg *kafka.Message) {
+	key := newPartitionKey(msg.TopicPartition)
+	if c.failures == nil {
+		c.failures = make(map[partitionKey]int)
+	}
+	c.failures[key]++
+
+	if err := c.consumer.Seek(msg.TopicPartition, 0); err != nil {
+		c.logger.Error("failed to rewind partition", append(messageAttrs(msg), slog.Any(LogKeyError, err))...)
+	}
+	sleep(retryDelay(c.failures[key]), ctx.Done())
+}
+
+// retryDelay возвращает паузу перед повторной обработкой после failures ошибок подряд
+func retryDelay(failures int) time.Duration {
+	delay := retryInitial << min(max(failures-1, 0), 16)
+	if delay > retryMax {
+		delay = retryMax
+	}
+	return delay
+}
+
+// sleep ждет d или закрытия done и сообщает, истекла ли пауза полностью
+func sleep(d time.Duration, done <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// poll получает событие с указанным таймаутом и возвращает сообщение, если оно получено
+func (c *Consumer) poll(timeoutMs int) (*kafka.Message, error) {
+	c.pollMu.Lock()
+	defer c.pollMu.Unlock()
+
+	if c.consumer.IsClosed() {
+		return nil, nil
+	}
+	switch ev := c.consumer.Poll(timeoutMs).(type) {
+	case *kafka.Message:
+		return ev, nil
+	case kafka.OAuthBearerTokenRefresh:
+		return nil, c.refreshToken()
+	case *kafka.Stats:
+		return nil, c.metrics.observeStats(ev)
+	case kafka.Error:
+		c.metrics.consumeFailed()
+		return nil, fmt.Errorf("error consuming message: %w", ev)
+	default:
+		return nil, nil // Таймаут или служебное событие - не ошибка
+	}
+}
+
+// loop получает и обрабатывает сообщения, пока консьюмер запущен и ctx не отменен
+func (c *Consumer) loop(ctx context.Context, handler Handler) {
+	handler = c.wrap(handler)
+
+	done := c.enterLoop()
+	defer done()
+
+	if c.workerQueueSize > 0 {
+		workers := newWorkerPool(ctx, c, handler, c.workerQueueSize, c.workersPerPartition,
+			c.highWater, c.lowWater)
+		c.workers.Store(workers)
+		defer func() {
+			// Дожидаемся обработки полученных сообщений не дольше времени остановки
+			drainCtx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+			defer cancel()
+
+			offsets, unfinished := workers.close(drainCtx)
+			c.commitOffsets(offsets)
+			c.setUnfinished(unfinished)
+			c.workers.Store(nil)
+		}()
+	}
+
+	for c.running.Load() && ctx.Err() == nil {
+		if err := c.consume(ctx, 100, handler); err != nil {
+			c.logger.Error("failed to consume message", slog.Any(LogKeyError, err))
 		}
-		return fmt.Errorf("error consuming message: %w", err)
 	}
+}
 
-	// Логируем полученное сообщение
-	c.logger.Printf("Получено сообщение из топика %s [%d] со смещением %v: %s",
-		*msg.TopicPartition.Topic, msg.TopicPartition.Partition, msg.TopicPartition.Offset, string(msg.Value))
+// Run обрабатывает сообщения до отмены ctx или возврата ErrStopConsuming из обработчика
+func (c *Consumer) Run(ctx context.Context, handler Handler) error {
+	c.running.Store(true)
+	c.logger.Info("consumer started", slog.Any("topics", c.topics))
 
-	// Обрабатываем сообщение, если предоставлен обработчик
-	if handler != nil {
-		continueProcessing := handler(msg)
-		if !continueProcessing {
-			c.running = false
+	c.loop(ctx, handler)
+
+	return ctx.Err()
+}
+
+// storeOffset сохраняет смещение для последующей фиксации
+func (c *Consumer) storeOffset(tp kafka.TopicPartition) {
+	if c.commitDisabled() {
+		return
+	}
+	if _, err := c.consumer.StoreOffsets([]kafka.TopicPartition{tp}); err != nil {
+		c.logger.Error("failed to store offset",
+			slog.String(LogKeyTopic, *tp.Topic), slog.Int(LogKeyPartition, int(tp.Partition)),
+			slog.Any(LogKeyOffset, tp.Offset), slog.Any(LogKeyError, err))
+	}
+}
+
+// commitOffsets синхронно фиксирует указанные позиции
+func (c *Consumer) commitOffsets(offsets []kafka.TopicPartition) {
+	if len(offsets) == 0 || c.commitDisabled() {
+		return
+	}
+	if _, err := c.consumer.CommitOffsets(offsets); err != nil {
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrNoOffset {
+			return
 		}
+		c.logger.Error("failed to commit offsets", slog.Any("offsets", offsets), slog.Any(LogKeyError, err))
 	}
+}
 
-	return nil
+// commitStored синхронно фиксирует все сохраненные, но еще не зафиксированные смещения
+func (c *Consumer) commitStored() {
+	if c.commitDisabled() {
+		return
+	}
+	if _, err := c.consumer.Commit(); err != nil {
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrNoOffset {
+			return
+		}
+		c.logger.Error("failed to commit stored offsets", slog.Any(LogKeyError, err))
+	}
+}
+
+// commitDisabled сообщает, что смещения не сохраняются и не фиксируются
+func (c *Consumer) commitDisabled() bool {
+	return c.noCommit || c.abandoned.Load()
+}
+
+// highWatermark возвращает закэшированную верхнюю границу партиции или -1, если она неизвестна
+func (c *Consumer) highWatermark(tp kafka.TopicPartition) int64 {
+	if c.metrics == nil || tp.Topic == nil {
+		return -1
+	}
+	_, high, err := c.consumer.GetWatermarkOffsets(*tp.Topic, tp.Partition)
+	if err != nil {
+		return -1
+	}
+	return high
+}
+
+// WatermarkOffsets запрашивает у брокера нижнюю и верхнюю границы партиции
+func (c *Consumer) WatermarkOffsets(topic string, partition int32) (low int64, high int64, err error) {
+	low, high, err = c.consumer.QueryWatermarkOffsets(topic, partition, int(metadataTimeout.Milliseconds()))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query watermark offsets of %s [%d]: %w", topic, partition, err)
+	}
+	return low, high, nil
+}
+
+// refreshToken обновляет OAuth-токен по запросу librdkafka
+func (c *Consumer) refreshToken() error {
+	if c.tokenProvider == nil {
+		return fmt.Errorf("OAuth token refresh requested but no token provider configured")
+	}
+	return refreshOAuthBearerToken(c.consumer, c.tokenProvider)
 }
 
 // Start запускает консьюмера в бесконечном цикле
 func (c *Consumer) Start(handler MessageHandler) {
-	c.running = true
-	c.logger.Printf("Начинаем слушать топики: %v", c.topics)
-	c.logger.Printf("Для выхода нажмите Ctrl+C")
-
-	for c.running {
-		if err := c.Consume(100, handler); err != nil {
-			c.logger.Printf("Ошибка при потреблении сообщения: %v", err)
-		}
+	var h Handler
+	if handler != nil {
+		h = handler.Handler()
 	}
+
+	c.running.Store(true)
+	c.logger.Info("consumer started", slog.Any("topics", c.topics))
+
+	c.loop(context.Background(), h)
 }
 
 // Stop останавливает консьюмера
 func (c *Consumer) Stop() {
-	c.running = false
-	c.logger.Printf("Остановка консьюмера")
-}
-
-// Close закрывает соединение с Kafka
-func (c *Consumer) Close() {
-	c.consumer.Close()
-	c.logger.Printf("Соединение с Kafka закрыто")
+	c.running.Store(false)
+	c.logger.Info("consumer stopping")
 }
 
 // StartWithTimeout запускает консьюмера на указанное время
@@ -118,17 +423,14 @@ func (c *Consumer) StartWithTimeout(handler MessageHandler, timeoutSeconds int)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
 	defer cancel()
 
-	c.running = true
-	c.logger.Printf("Начинаем слушать топики с таймаутом %d секунд: %v", timeoutSeconds, c.topics)
+	var h Handler
+	if handler != nil {
+		h = handler.Handler()
+	}
 
-	go func() {
-		<-ctx.Done()
-		c.Stop()
-	}()
+	c.running.Store(true)
+	c.logger.Info("consumer started",
+		slog.Any("topics", c.topics), slog.Int("timeout_seconds", timeoutSeconds))
 
-	for c.running {
-		if err := c.Consume(100, handler); err != nil {
-			c.logger.Printf("Ошибка при потреблении сообщения: %v", err)
-		}
-	}
-}
\ No newline at end of file
+	c.loop(ctx, h)
+}
@@ -0,0 +1,162 @@
+package kafka
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Заголовки отложенных сообщений
+const (
+	// DelayTargetHeader - топик, в который сообщение пересылается в срок
+	DelayTargetHeader = "delay-target"
+	// DelayUntilHeader - время доставки (Unix, мс)
+	DelayUntilHeader = "delay-until"
+	// DelayIDHeader - идентификатор отложенного сообщения; сохраняется при пересылке,
+	// поэтому повторы, возможные при сбое планировщика, отбрасываются через
+	// Deduplicate(HeaderID(DelayIDHeader), store)
+	DelayIDHeader = "delay-id"
+	// DelayPartitionHeader - партиция целевого топика, выбранная Partitioner продюсера;
+	// без заголовка партицию при пересылке выбирает librdkafka
+	DelayPartitionHeader = "delay-partition"
+)
+
+// DelayBuckets описывает топики отложенной доставки: сообщение с задержкой d ожидает
+// в топике <Prefix>-<d> не меньше d с момента записи. Задержки кратны секунде
+type DelayBuckets struct {
+	Prefix string
+	Delays []time.Duration
+}
+
+// DefaultDelayBuckets - топики delay-10s, delay-1m, delay-10m, delay-1h и delay-24h
+var DefaultDelayBuckets = DelayBuckets{
+	Prefix: "delay",
+	Delays: []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute, time.Hour, 24 * time.Hour},
+}
+
+// WithDelayBuckets задает топики отложенной доставки для Producer.SendAt;
+// по умолчанию используется DefaultDelayBuckets
+func WithDelayBuckets(buckets DelayBuckets) Option {
+	return func(o *options) {
+		o.delayBuckets = buckets
+	}
+}
+
+// Topic возвращает топик задержки delay
+func (b DelayBuckets) Topic(delay time.Duration) string {
+	switch {
+	case delay%time.Hour == 0:
+		return fmt.Sprintf("%s-%dh", b.Prefix, delay/time.Hour)
+	case delay%time.Minute == 0:
+		return fmt.Sprintf("%s-%dm", b.Prefix, delay/time.Minute)
+	default:
+		return fmt.Sprintf("%s-%ds", b.Prefix, delay/time.Second)
+	}
+}
+
+// Topics возвращает топики всех задержек
+func (b DelayBuckets) Topics() []string {
+	topics := make([]string, len(b.Delays))
+	for i, delay := range b.Delays {
+		topics[i] = b.Topic(delay)
+	}
+	return topics
+}
+
+// Delay возвращает задержку топика topic
+func (b DelayBuckets) Delay(topic string) (time.Duration, bool) {
+	for _, delay := range b.Delays {
+		if b.Topic(delay) == topic {
+			return delay, true
+		}
+	}
+	return 0, false
+}
+
+// Bucket выбирает задержку для оставшегося времени remaining: наибольшую из не превышающих
+// remaining, чтобы сообщение не ожидало дольше нужного, или наименьшую, если все больше
+func (b DelayBuckets) Bucket(remaining time.Duration) time.Duration {
+	delays := append([]time.Duration(nil), b.Delays...)
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+
+	bucket := delays[0]
+	for _, delay := range delays {
+		if delay <= remaining {
+			bucket = delay
+		}
+	}
+	return bucket
+}
+
+// Validate проверяет, что задержки заданы, положительны и кратны секунде
+func (b DelayBuckets) Validate() error {
+	if b.Prefix == "" || len(b.Delays) == 0 {
+		return fmt.Errorf("delay buckets require prefix and delays")
+	}
+	for _, delay := range b.Delays {
+		if delay <= 0 || delay%time.Second != 0 {
+			return fmt.Errorf("invalid delay bucket %s: must be a positive whole number of seconds", delay)
+		}
+	}
+	return nil
+}
+
+// SendAt отправляет сообщение, которое будет доставлено в топик продюсера не раньше at.
+// Сообщение записывается в топик задержки с заголовками delay-target и delay-until,
+// а пересылает его планировщик (пакет scheduler). Если у продюсера задан Partitioner,
+// партиция целевого топика выбирается сразу и передается в заголовке delay-partition,
+// поэтому отложенное сообщение попадает в ту же партицию, что и отправленное Send
+// с тем же ключом. Если at уже наступило, сообщение отправляется в топик продюсера сразу
+func (p *Producer) SendAt(ctx context.Context, record Record, at time.Time) error {
+	if p.topic == "" {
+		return ErrNoTopic
+	}
+	remaining := time.Until(at)
+	if remaining <= 0 {
+		return p.SendRecord(ctx, record)
+	}
+	if err := p.delayBuckets.Validate(); err != nil {
+		return err
+	}
+
+	id, err := delayID()
+	if err != nil {
+		return err
+	}
+
+	headers := append([]kafka.Header(nil), record.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: DelayTargetHeader, Value: []byte(p.topic)},
+		kafka.Header{Key: DelayUntilHeader, Value: []byte(strconv.FormatInt(at.UnixMilli(), 10))},
+		kafka.Header{Key: DelayIDHeader, Value: []byte(id)},
+	)
+	if p.partitioner != nil {
+		partition := p.partitioner.Partition(p.topic, record.Key, p.partitions.get())
+		headers = append(headers, kafka.Header{Key: DelayPartitionHeader, Value: []byte(strconv.Itoa(int(partition)))})
+	}
+
+	// Временная метка - начало ожидания в топике задержки
+	topic := p.delayBuckets.Topic(p.delayBuckets.Bucket(remaining))
+	return p.SendMessage(ctx, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            record.Key,
+		Value:          record.Value,
+		Headers:        headers,
+		Timestamp:      time.Now(),
+	}, nil)
+}
+
+// delayID возвращает случайный идентификатор отложенного сообщения
+func delayID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate delay id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
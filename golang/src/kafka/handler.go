@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// ErrStopConsuming возвращается обработчиком, чтобы остановить консьюмера
+// (аналог возврата false из MessageHandler)
+var ErrStopConsuming = errors.New("stop consuming")
+
+// Handler обрабатывает сообщение; ошибка означает, что сообщение не обработано
+// и будет обработано повторно
+type Handler func(ctx context.Context, msg *kafka.Message) error
+
+// Middleware оборачивает Handler дополнительным поведением
+type Middleware func(Handler) Handler
+
+// Handler преобразует MessageHandler в Handler
+func (h MessageHandler) Handler() Handler {
+	return func(ctx context.Context, msg *kafka.Message) error {
+		if !h(msg) {
+			return ErrStopConsuming
+		}
+		return nil
+	}
+}
+
+// Chain оборачивает обработчик цепочкой middleware;
+// первая middleware в списке выполняется первой
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Use добавляет middleware в конец цепочки обработки консьюмера
+func (c *Consumer) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// wrap строит итоговый обработчик: встроенные middleware трассировки, метрик
+// и логирования, затем пользовательские middleware
+func (c *Consumer) wrap(handler Handler) Handler {
+	if handler == nil {
+		handler = func(context.Context, *kafka.Message) error { return nil }
+	}
+
+	var middlewares []Middleware
+	if c.tracing != nil {
+		middlewares = append(middlewares, c.tracing.middleware(c.group))
+	}
+	if c.metrics != nil {
+		middlewares = append(middlewares, c.metrics.Middleware())
+	}
+	middlewares = append(middlewares, Logging(c.logger))
+	middlewares = append(middlewares, c.middlewares...)
+
+	return Chain(handler, middlewares...)
+}
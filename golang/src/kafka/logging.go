@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Ключи структурированных полей в логах
+const (
+	LogKeyTopic     = "topic"
+	LogKeyPartition = "partition"
+	LogKeyOffset    = "offset"
+	LogKeyKey       = "key"
+	LogKeyGroup     = "group"
+	LogKeyLatency   = "latency"
+	LogKeyError     = "error"
+)
+
+// NewLogger создает структурированный логгер с указанным уровнем;
+// при json=true записи выводятся в формате JSON, иначе в формате key=value
+func NewLogger(w io.Writer, level slog.Leveler, json bool) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if json {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// WithLibrdkafkaLogs перенаправляет внутренние логи librdkafka в логгер клиента
+func WithLibrdkafkaLogs() Option {
+	return func(o *options) {
+		o.librdkafkaLogs = true
+	}
+}
+
+// loggerOrDefault возвращает переданный логгер или slog.Default(), если он не задан
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// messageAttrs возвращает структурированные поля сообщения для логирования
+func messageAttrs(msg *kafka.Message) []any {
+	return []any{
+		slog.String(LogKeyTopic, topicName(msg)),
+		slog.Int(LogKeyPartition, int(msg.TopicPartition.Partition)),
+		slog.Any(LogKeyOffset, msg.TopicPartition.Offset),
+		slog.String(LogKeyKey, string(msg.Key)),
+	}
+}
+
+// forwardLibrdkafkaLogs пересылает события из канала логов librdkafka в логгер
+// до закрытия канала при закрытии клиента
+func forwardLibrdkafkaLogs(logs chan kafka.LogEvent, logger *slog.Logger) {
+	logger = logger.With(slog.String("source", "librdkafka"))
+	for ev := range logs {
+		logger.LogAttrs(context.Background(), syslogLevel(ev.Level), ev.Message,
+			slog.String("client", ev.Name),
+			slog.String("tag", ev.Tag),
+			slog.Time("rdkafka_time", ev.Timestamp),
+		)
+	}
+}
+
+// syslogLevel преобразует уровень syslog из librdkafka в уровень slog
+func syslogLevel(level int) slog.Level {
+	switch {
+	case level <= 3:
+		return slog.LevelError
+	case level == 4:
+		return slog.LevelWarn
+	case level <= 6:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
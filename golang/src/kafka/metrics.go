@@ -0,0 +1,265 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics - набор Prometheus-метрик продюсера и консьюмера.
+// Один экземпляр можно использовать в нескольких клиентах одного процесса
+type Metrics struct {
+	produced        *prometheus.CounterVec
+	consumed        *prometheus.CounterVec
+	failed          *prometheus.CounterVec
+	deliveryLatency *prometheus.HistogramVec
+	handlerDuration *prometheus.HistogramVec
+	queueSize       *prometheus.GaugeVec
+	consumerLag     *prometheus.GaugeVec
+
+	// Метрики из статистики librdkafka (statistics.interval.ms)
+	brokerRTT      *prometheus.GaugeVec
+	brokerOutbuf   *prometheus.GaugeVec
+	brokerWaitresp *prometheus.GaugeVec
+	txMsgs         *prometheus.GaugeVec
+	rxMsgs         *prometheus.GaugeVec
+	queueMessages  *prometheus.GaugeVec
+}
+
+// WithMetrics включает сбор Prometheus-метрик клиентом
+func WithMetrics(metrics *Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// NewMetrics создает метрики с указанным префиксом и регистрирует их в registerer.
+// Если registerer равен nil, используется prometheus.DefaultRegisterer
+func NewMetrics(namespace string, registerer prometheus.Registerer) (*Metrics, error) {
+	if namespace == "" {
+		namespace = "kafka"
+	}
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	m := &Metrics{
+		produced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_produced_total",
+			Help:      "Number of messages successfully delivered to the broker.",
+		}, []string{"topic"}),
+		consumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_consumed_total",
+			Help:      "Number of messages received by the consumer.",
+		}, []string{"topic"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_failed_total",
+			Help:      "Number of failed produce, delivery and consume operations.",
+		}, []string{"topic", "operation"}),
+		deliveryLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "delivery_latency_seconds",
+			Help:      "Time from producing a message to its delivery report.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+		}, []string{"topic"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Time spent in the message handler.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+		}, []string{"topic"}),
+		queueSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "producer_queue_messages",
+			Help:      "Messages waiting to be delivered or to have their delivery report handled.",
+		}, []string{"topic"}),
+		consumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "consumer_lag_messages",
+			Help:      "Difference between the high watermark and the consumed offset.",
+		}, []string{"topic", "partition"}),
+		brokerRTT: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "broker_rtt_seconds",
+			Help:      "Average broker round-trip time reported by librdkafka.",
+		}, []string{"client", "broker"}),
+		brokerOutbuf: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "broker_outbuf_requests",
+			Help:      "Requests awaiting transmission to the broker.",
+		}, []string{"client", "broker"}),
+		brokerWaitresp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "broker_waitresp_requests",
+			Help:      "Requests in flight awaiting a broker response.",
+		}, []string{"client", "broker"}),
+		txMsgs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "client_txmsgs",
+			Help:      "Total messages transmitted (produced) reported by librdkafka.",
+		}, []string{"client"}),
+		rxMsgs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "client_rxmsgs",
+			Help:      "Total messages received (consumed) reported by librdkafka.",
+		}, []string{"client"}),
+		queueMessages: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "client_queue_messages",
+			Help:      "Messages currently in librdkafka queues.",
+		}, []string{"client"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.produced, m.consumed, m.failed, m.deliveryLatency, m.handlerDuration,
+		m.queueSize, m.consumerLag, m.brokerRTT, m.brokerOutbuf, m.brokerWaitresp,
+		m.txMsgs, m.rxMsgs, m.queueMessages,
+	}
+	for _, c := range collectors {
+		if err := registerer.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+// Методы ниже допускают nil-получатель, чтобы клиенты без метрик не проверяли их наличие
+
+// produceFailed учитывает ошибку постановки сообщения в очередь
+func (m *Metrics) produceFailed(topic string) {
+	if m == nil {
+		return
+	}
+	m.failed.WithLabelValues(topic, "produce").Inc()
+}
+
+// delivered учитывает отчет о доставке сообщения; задержка доставки отсчитывается
+// от enqueuedAt - времени постановки сообщения в очередь, если оно известно
+func (m *Metrics) delivered(msg *kafka.Message, enqueuedAt time.Time) {
+	if m == nil {
+		return
+	}
+	topic := topicName(msg)
+	if msg.TopicPartition.Error != nil {
+		m.failed.WithLabelValues(topic, "deliver").Inc()
+		return
+	}
+	m.produced.WithLabelValues(topic).Inc()
+	if !enqueuedAt.IsZero() {
+		m.deliveryLatency.WithLabelValues(topic).Observe(time.Since(enqueuedAt).Seconds())
+	}
+}
+
+// setQueueSize обновляет размер очереди продюсера
+func (m *Metrics) setQueueSize(topic string, size int) {
+	if m == nil {
+		return
+	}
+	m.queueSize.WithLabelValues(topic).Set(float64(size))
+}
+
+// consumeFailed учитывает ошибку получения сообщения
+func (m *Metrics) consumeFailed() {
+	if m == nil {
+		return
+	}
+	m.failed.WithLabelValues("", "consume").Inc()
+}
+
+// received учитывает полученное сообщение и отставание партиции,
+// highWatermark берется из локального кэша консьюмера
+func (m *Metrics) received(msg *kafka.Message, highWatermark int64) {
+	if m == nil {
+		return
+	}
+	topic := topicName(msg)
+	m.consumed.WithLabelValues(topic).Inc()
+	if highWatermark >= 0 && msg.TopicPartition.Offset >= 0 {
+		lag := highWatermark - int64(msg.TopicPartition.Offset) - 1
+		if lag < 0 {
+			lag = 0
+		}
+		m.consumerLag.WithLabelValues(topic, strconv.Itoa(int(msg.TopicPartition.Partition))).Set(float64(lag))
+	}
+}
+
+// handled учитывает время работы обработчика
+func (m *Metrics) handled(msg *kafka.Message, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.handlerDuration.WithLabelValues(topicName(msg)).Observe(duration.Seconds())
+}
+
+// rdkafkaStats - используемая часть JSON-статистики librdkafka
+type rdkafkaStats struct {
+	Name    string `json:"name"`
+	MsgCnt  int64  `json:"msg_cnt"`
+	TxMsgs  int64  `json:"txmsgs"`
+	RxMsgs  int64  `json:"rxmsgs"`
+	Brokers map[string]struct {
+		Name        string `json:"name"`
+		OutbufCnt   int64  `json:"outbuf_cnt"`
+		WaitrespCnt int64  `json:"waitresp_cnt"`
+		RTT         struct {
+			Avg int64 `json:"avg"`
+		} `json:"rtt"`
+	} `json:"brokers"`
+	Topics map[string]struct {
+		Partitions map[string]struct {
+			Partition   int32 `json:"partition"`
+			ConsumerLag int64 `json:"consumer_lag"`
+		} `json:"partitions"`
+	} `json:"topics"`
+}
+
+// observeStats разбирает статистику librdkafka и обновляет метрики
+func (m *Metrics) observeStats(stats *kafka.Stats) error {
+	if m == nil {
+		return nil
+	}
+
+	var s rdkafkaStats
+	if err := json.Unmarshal([]byte(stats.String()), &s); err != nil {
+		return fmt.Errorf("failed to parse librdkafka statistics: %w", err)
+	}
+
+	m.txMsgs.WithLabelValues(s.Name).Set(float64(s.TxMsgs))
+	m.rxMsgs.WithLabelValues(s.Name).Set(float64(s.RxMsgs))
+	m.queueMessages.WithLabelValues(s.Name).Set(float64(s.MsgCnt))
+
+	for _, b := range s.Brokers {
+		// RTT передается в микросекундах
+		m.brokerRTT.WithLabelValues(s.Name, b.Name).Set(float64(b.RTT.Avg) / 1e6)
+		m.brokerOutbuf.WithLabelValues(s.Name, b.Name).Set(float64(b.OutbufCnt))
+		m.brokerWaitresp.WithLabelValues(s.Name, b.Name).Set(float64(b.WaitrespCnt))
+	}
+
+	for topic, t := range s.Topics {
+		for _, p := range t.Partitions {
+			// Служебная партиция -1 и партиции без данных об отставании пропускаются
+			if p.Partition < 0 || p.ConsumerLag < 0 {
+				continue
+			}
+			m.consumerLag.WithLabelValues(topic, strconv.Itoa(int(p.Partition))).Set(float64(p.ConsumerLag))
+		}
+	}
+
+	return nil
+}
+
+// topicName возвращает имя топика сообщения
+func topicName(msg *kafka.Message) string {
+	if msg.TopicPartition.Topic == nil {
+		return ""
+	}
+	return *msg.TopicPartition.Topic
+}
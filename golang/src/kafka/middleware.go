@@ -0,0 +1,290 @@
+package kafka
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Recover перехватывает панику в обработчике и возвращает ее как ошибку
+func Recover(logger *slog.Logger) Middleware {
+	logger = loggerOrDefault(logger)
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *kafka.Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("handler panicked",
+						append(messageAttrs(msg), slog.Any("panic", r), slog.String("stack", string(debug.Stack())))...)
+					err = fmt.Errorf("handler panicked: %v", r)
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Logging логирует получение сообщения и результат его обработки
+func Logging(logger *slog.Logger) Middleware {
+	logger = loggerOrDefault(logger)
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *kafka.Message) error {
+			logger.InfoContext(ctx, "message received", messageAttrs(msg)...)
+
+			started := time.Now()
+			err := next(ctx, msg)
+
+			attrs := append(messageAttrs(msg), slog.Duration(LogKeyLatency, time.Since(started)))
+			if err != nil && !errors.Is(err, ErrStopConsuming) {
+				logger.ErrorContext(ctx, "message handling failed", append(attrs, slog.Any(LogKeyError, err))...)
+			} else {
+				logger.DebugContext(ctx, "message handled", attrs...)
+			}
+			return err
+		}
+	}
+}
+
+// Middleware возвращает middleware, учитывающую время и ошибки обработки
+func (m *Metrics) Middleware() Middleware {
+	return func(next Handler) Handler {
+		if m == nil {
+			return next
+		}
+		return func(ctx context.Context, msg *kafka.Message) error {
+			started := time.Now()
+			err := next(ctx, msg)
+			m.handled(msg, time.Since(started))
+			if err != nil && !errors.Is(err, ErrStopConsuming) {
+				m.failed.WithLabelValues(topicName(msg), "handle").Inc()
+			}
+			return err
+		}
+	}
+}
+
+// middleware возвращает middleware, создающую span обработки вокруг обработчика
+func (t *tracing) middleware(group string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *kafka.Message) error {
+			ctx, span := t.startConsumerSpan(ctx, msg, group)
+			err := next(ctx, msg)
+			if errors.Is(err, ErrStopConsuming) {
+				endSpan(span, nil)
+			} else {
+				endSpan(span, err)
+			}
+			return err
+		}
+	}
+}
+
+// Timeout ограничивает время обработки сообщения; обработчик должен учитывать ctx
+func Timeout(timeout time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *kafka.Message) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			err := next(ctx, msg)
+			if err == nil && ctx.Err() != nil {
+				return fmt.Errorf("handler exceeded timeout %s: %w", timeout, ctx.Err())
+			}
+			return err
+		}
+	}
+}
+
+// valueKey - ключ контекста для десериализованного значения сообщения
+type valueKey struct{}
+
+// Deserialize десериализует значение сообщения и передает результат обработчику через контекст;
+// значение можно получить с помощью Value
+func Deserialize[T any](decode func([]byte) (T, error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *kafka.Message) error {
+			value, err := decode(msg.Value)
+			if err != nil {
+				return fmt.Errorf("failed to deserialize message: %w", err)
+			}
+			return next(context.WithValue(ctx, valueKey{}, value), msg)
+		}
+	}
+}
+
+// Value возвращает значение, десериализованное middleware Deserialize
+func Value[T any](ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(valueKey{}).(T)
+	return value, ok
+}
+
+// MessageIDFunc возвращает идентификатор сообщения для дедупликации
+type MessageIDFunc func(msg *kafka.Message) string
+
+// OffsetID использует в качестве идентификатора топик, партицию и смещение сообщения
+func OffsetID(msg *kafka.Message) string {
+	return fmt.Sprintf("%s/%d/%d", topicName(msg), msg.TopicPartition.Partition, msg.TopicPartition.Offset)
+}
+
+// KeyID использует в качестве идентификатора ключ сообщения
+func KeyID(msg *kafka.Message) string {
+	return string(msg.Key)
+}
+
+// HeaderID использует в качестве идентификатора значение заголовка name
+// (например, outbox-id или идентификатор события, назначенный продюсером)
+func HeaderID(name string) MessageIDFunc {
+	return func(msg *kafka.Message) string {
+		for _, h := range msg.Headers {
+			if h.Key == name {
+				return string(h.Value)
+			}
+		}
+		return ""
+	}
+}
+
+// FieldID использует в качестве идентификатора поле name JSON-значения сообщения:
+// строки без кавычек, остальные типы в виде JSON
+func FieldID(name string) MessageIDFunc {
+	return func(msg *kafka.Message) string {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(msg.Value, &fields); err != nil {
+			return ""
+		}
+		field, ok := fields[name]
+		if !ok {
+			return ""
+		}
+		var s string
+		if err := json.Unmarshal(field, &s); err == nil {
+			return s
+		}
+		return string(field)
+	}
+}
+
+// DedupStore хранит идентификаторы уже обработанных сообщений
+type DedupStore interface {
+	// Seen сообщает, было ли сообщение с указанным идентификатором обработано
+	Seen(ctx context.Context, id string) (bool, error)
+	// Mark отмечает сообщение как обработанное
+	Mark(ctx context.Context, id string) error
+}
+
+// Deduplicate пропускает сообщения, которые уже были успешно обработаны.
+// Сообщения с пустым идентификатором обрабатываются без проверки
+func Deduplicate(id MessageIDFunc, store DedupStore) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *kafka.Message) error {
+			messageID := id(msg)
+			if messageID == "" {
+				return next(ctx, msg)
+			}
+
+			seen, err := store.Seen(ctx, messageID)
+			if err != nil {
+				return fmt.Errorf("failed to check message id: %w", err)
+			}
+			if seen {
+				return nil
+			}
+
+			if err := next(ctx, msg); err != nil {
+				return err
+			}
+
+			if err := store.Mark(ctx, messageID); err != nil {
+				return fmt.Errorf("failed to mark message id: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// MemoryDedupStore хранит в памяти ограниченное число идентификаторов,
+// вытесняя давно не встречавшиеся (LRU) и, если задан ttl, устаревшие
+type MemoryDedupStore struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// dedupEntry - идентификатор и время его отметки
+type dedupEntry struct {
+	id     string
+	marked time.Time
+}
+
+// NewMemoryDedupStore создает хранилище на size идентификаторов; size <= 0 - без ограничения
+func NewMemoryDedupStore(size int) *MemoryDedupStore {
+	return NewMemoryDedupStoreWithTTL(size, 0)
+}
+
+// NewMemoryDedupStoreWithTTL создает хранилище на size идентификаторов,
+// каждый из которых хранится не дольше ttl. size <= 0 - без ограничения числа,
+// тогда идентификаторы удаляются только по ttl; ttl 0 - без ограничения времени
+func NewMemoryDedupStoreWithTTL(size int, ttl time.Duration) *MemoryDedupStore {
+	return &MemoryDedupStore{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Seen сообщает, есть ли идентификатор в хранилище
+func (s *MemoryDedupStore) Seen(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[id]
+	if !ok {
+		return false, nil
+	}
+	if s.expired(elem) {
+		s.order.Remove(elem)
+		delete(s.entries, id)
+		return false, nil
+	}
+	s.order.MoveToBack(elem)
+	return true, nil
+}
+
+// Mark добавляет идентификатор, вытесняя давно не встречавшиеся при переполнении
+// и устаревшие из начала очереди
+func (s *MemoryDedupStore) Mark(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := dedupEntry{id: id, marked: time.Now()}
+	if elem, ok := s.entries[id]; ok {
+		elem.Value = entry
+		s.order.MoveToBack(elem)
+		return nil
+	}
+	s.entries[id] = s.order.PushBack(entry)
+	for oldest := s.order.Front(); oldest != nil; oldest = s.order.Front() {
+		full := s.size > 0 && s.order.Len() > s.size
+		if !full && !s.expired(oldest) {
+			break
+		}
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(dedupEntry).id)
+	}
+	return nil
+}
+
+// expired сообщает, что идентификатор хранится дольше ttl
+func (s *MemoryDedupStore) expired(elem *list.Element) bool {
+	return s.ttl > 0 && time.Since(elem.Value.(dedupEntry).marked) > s.ttl
+}
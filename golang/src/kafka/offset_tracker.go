@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// offsetTracker отслеживает завершение обработки сообщений одной партиции
+// и вычисляет смещение, до которого все сообщения обработаны без пропусков
+type offsetTracker struct {
+	mu sync.Mutex
+	// inFlight - смещения в порядке получения, еще не вошедшие в непрерывный префикс
+	inFlight []kafka.Offset
+	// completed - завершенные смещения из inFlight
+	completed map[kafka.Offset]bool
+	// position - следующее смещение для фиксации или kafka.OffsetInvalid
+	position kafka.Offset
+}
+
+// newOffsetTracker создает пустой трекер
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		completed: make(map[kafka.Offset]bool),
+		position:  kafka.OffsetInvalid,
+	}
+}
+
+// add регистрирует полученное сообщение; смещения должны поступать по возрастанию
+func (t *offsetTracker) add(offset kafka.Offset) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight = append(t.inFlight, offset)
+}
+
+// done отмечает сообщение обработанным и возвращает новую позицию фиксации,
+// если непрерывный префикс обработанных сообщений вырос
+func (t *offsetTracker) done(offset kafka.Offset) (kafka.Offset, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.completed[offset] = true
+
+	advanced := false
+	for len(t.inFlight) > 0 && t.completed[t.inFlight[0]] {
+		delete(t.completed, t.inFlight[0])
+		t.position = t.inFlight[0] + 1
+		t.inFlight = t.inFlight[1:]
+		advanced = true
+	}
+
+	return t.position, advanced
+}
+
+// committable возвращает позицию, до которой можно зафиксировать смещения
+func (t *offsetTracker) committable() (kafka.Offset, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.position, t.position != kafka.OffsetInvalid
+}
+
+// pending возвращает первое незавершенное смещение и число незавершенных сообщений
+func (t *offsetTracker) pending() (kafka.Offset, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.inFlight) == 0 {
+		return kafka.OffsetInvalid, 0
+	}
+	return t.inFlight[0], len(t.inFlight) - len(t.completed)
+}
+
+// size возвращает число полученных, но еще не обработанных сообщений
+func (t *offsetTracker) size() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.inFlight) - len(t.completed)
+}
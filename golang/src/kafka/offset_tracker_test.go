@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	// step - завершение смещения и ожидаемая позиция фиксации после него
+	type step struct {
+		done     kafka.Offset
+		position kafka.Offset
+		advanced bool
+	}
+
+	tests := []struct {
+		name    string
+		added   []kafka.Offset
+		steps   []step
+		pending kafka.Offset
+		size    int
+	}{
+		{
+			name:  "in order",
+			added: []kafka.Offset{10, 11, 12},
+			steps: []step{
+				{done: 10, position: 11, advanced: true},
+				{done: 11, position: 12, advanced: true},
+				{done: 12, position: 13, advanced: true},
+			},
+			pending: kafka.OffsetInvalid,
+		},
+		{
+			name:  "reverse order",
+			added: []kafka.Offset{10, 11, 12},
+			steps: []step{
+				{done: 12, position: kafka.OffsetInvalid},
+				{done: 11, position: kafka.OffsetInvalid},
+				{done: 10, position: 13, advanced: true},
+			},
+			pending: kafka.OffsetInvalid,
+		},
+		{
+			name:  "gap blocks position",
+			added: []kafka.Offset{10, 11, 12, 13},
+			steps: []step{
+				{done: 10, position: 11, advanced: true},
+				{done: 12, position: 11},
+				{done: 13, position: 11},
+			},
+			pending: 11,
+			size:    1,
+		},
+		{
+			name:  "gap filled",
+			added: []kafka.Offset{10, 11, 12, 13},
+			steps: []step{
+				{done: 11, position: kafka.OffsetInvalid},
+				{done: 13, position: kafka.OffsetInvalid},
+				{done: 10, position: 12, advanced: true},
+				{done: 12, position: 14, advanced: true},
+			},
+			pending: kafka.OffsetInvalid,
+		},
+		{
+			name:  "compacted offsets",
+			added: []kafka.Offset{5, 8, 20},
+			steps: []step{
+				{done: 8, position: kafka.OffsetInvalid},
+				{done: 5, position: 9, advanced: true},
+			},
+			pending: 20,
+			size:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newOffsetTracker()
+			for _, offset := range tt.added {
+				tracker.add(offset)
+			}
+
+			for _, s := range tt.steps {
+				position, advanced := tracker.done(s.done)
+				if position != s.position || advanced != s.advanced {
+					t.Fatalf("done(%d) = %d, %v; want %d, %v", s.done, position, advanced, s.position, s.advanced)
+				}
+			}
+
+			if offset, size := tracker.pending(); offset != tt.pending || size != tt.size {
+				t.Errorf("pending() = %d, %d; want %d, %d", offset, size, tt.pending, tt.size)
+			}
+			if size := tracker.size(); size != tt.size {
+				t.Errorf("size() = %d; want %d", size, tt.size)
+			}
+			last := tt.steps[len(tt.steps)-1].position
+			if position, ok := tracker.committable(); position != last || ok != (last != kafka.OffsetInvalid) {
+				t.Errorf("committable() = %d, %v; want %d", position, ok, last)
+			}
+		})
+	}
+}
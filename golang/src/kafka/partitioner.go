@@ -0,0 +1,240 @@
+package kafka
+
+import (
+	"fmt"
+	"hash/crc32"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+const (
+	// partitionRefreshInterval - период обновления числа партиций топика
+	partitionRefreshInterval = 30 * time.Second
+	// metadataTimeout - время ожидания метаданных топика
+	metadataTimeout = 10 * time.Second
+)
+
+// Partitioner выбирает партицию для сообщения
+type Partitioner interface {
+	// Partition возвращает номер партиции в диапазоне [0, partitions) для сообщения с ключом key
+	Partition(topic string, key []byte, partitions int) int32
+}
+
+// PartitionerFunc позволяет использовать функцию в качестве Partitioner
+type PartitionerFunc func(topic string, key []byte, partitions int) int32
+
+// Partition вызывает f
+func (f PartitionerFunc) Partition(topic string, key []byte, partitions int) int32 {
+	return f(topic, key, partitions)
+}
+
+// WithPartitioner задает выбор партиции на стороне Producer; число партиций топика
+// берется из метаданных и периодически обновляется, чтобы учесть добавленные партиции
+func WithPartitioner(partitioner Partitioner) Option {
+	return func(o *options) {
+		o.partitioner = partitioner
+	}
+}
+
+// Murmur2Partitioner выбирает партицию по хешу murmur2 ключа так же, как Java-клиент,
+// поэтому сообщения с одинаковым ключом попадают в ту же партицию, что и из Java-сервисов.
+// Сообщения без ключа распределяются случайно
+func Murmur2Partitioner() Partitioner {
+	return PartitionerFunc(func(_ string, key []byte, partitions int) int32 {
+		if len(key) == 0 {
+			return rand.Int31n(int32(partitions))
+		}
+		return int32((murmur2(key) & 0x7fffffff) % uint32(partitions))
+	})
+}
+
+// ConsistentRandomPartitioner выбирает партицию по CRC32 ключа, как partitioner
+// consistent_random в librdkafka; сообщения без ключа распределяются случайно
+func ConsistentRandomPartitioner() Partitioner {
+	return PartitionerFunc(func(_ string, key []byte, partitions int) int32 {
+		if len(key) == 0 {
+			return rand.Int31n(int32(partitions))
+		}
+		return int32(crc32.ChecksumIEEE(key) % uint32(partitions))
+	})
+}
+
+// RoundRobinPartitioner распределяет сообщения по партициям по очереди независимо от ключа
+func RoundRobinPartitioner() Partitioner {
+	var counter atomic.Uint32
+	return PartitionerFunc(func(_ string, _ []byte, partitions int) int32 {
+		return int32((counter.Add(1) - 1) % uint32(partitions))
+	})
+}
+
+// StickyPartitioner отправляет сообщения без ключа в одну случайную партицию, пока не будет
+// отправлено batchSize сообщений, после чего выбирает другую; это позволяет собирать
+// более крупные пакеты. Сообщения с ключом распределяются как в Murmur2Partitioner
+func StickyPartitioner(batchSize int) Partitioner {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &stickyPartitioner{batchSize: batchSize, keyed: Murmur2Partitioner()}
+}
+
+// stickyPartitioner - реализация StickyPartitioner
+type stickyPartitioner struct {
+	batchSize int
+	keyed     Partitioner
+
+	mu      sync.Mutex
+	current int32
+	sent    int
+}
+
+// Partition возвращает текущую партицию для сообщений без ключа
+func (s *stickyPartitioner) Partition(topic string, key []byte, partitions int) int32 {
+	if len(key) > 0 {
+		return s.keyed.Partition(topic, key, partitions)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sent == 0 || s.sent >= s.batchSize || int(s.current) >= partitions {
+		next := rand.Int31n(int32(partitions))
+		// Переходим на другую партицию, если она есть
+		if partitions > 1 && next == s.current && s.sent > 0 {
+			next = (next + 1) % int32(partitions)
+		}
+		s.current = next
+		s.sent = 0
+	}
+	s.sent++
+	return s.current
+}
+
+// murmur2 вычисляет 32-битный хеш murmur2 с теми же параметрами, что и Java-клиент Kafka
+func murmur2(data []byte) uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// partitionCount хранит число партиций топика и периодически обновляет его из метаданных
+type partitionCount struct {
+	producer *kafka.Producer
+	topic    string
+	logger   *slog.Logger
+	count    atomic.Int32
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newPartitionCount получает число партиций топика и запускает его обновление
+func newPartitionCount(producer *kafka.Producer, topic string, logger *slog.Logger) (*partitionCount, error) {
+	pc := &partitionCount{
+		producer: producer,
+		topic:    topic,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	count, err := pc.fetch()
+	if err != nil {
+		return nil, err
+	}
+	pc.count.Store(int32(count))
+
+	go pc.refresh()
+	return pc, nil
+}
+
+// get возвращает текущее число партиций
+func (pc *partitionCount) get() int {
+	return int(pc.count.Load())
+}
+
+// fetch запрашивает число партиций топика из метаданных
+func (pc *partitionCount) fetch() (int, error) {
+	md, err := pc.producer.GetMetadata(&pc.topic, false, int(metadataTimeout.Milliseconds()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metadata for topic %s: %w", pc.topic, err)
+	}
+
+	topic, ok := md.Topics[pc.topic]
+	if !ok {
+		return 0, fmt.Errorf("topic %s not found in metadata", pc.topic)
+	}
+	if topic.Error.Code() != kafka.ErrNoError {
+		return 0, fmt.Errorf("failed to get metadata for topic %s: %w", pc.topic, topic.Error)
+	}
+	if len(topic.Partitions) == 0 {
+		return 0, fmt.Errorf("topic %s has no partitions", pc.topic)
+	}
+	return len(topic.Partitions), nil
+}
+
+// refresh обновляет число партиций до вызова close
+func (pc *partitionCount) refresh() {
+	defer close(pc.done)
+
+	ticker := time.NewTicker(partitionRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			count, err := pc.fetch()
+			if err != nil {
+				pc.logger.Warn("failed to refresh partition count", slog.Any(LogKeyError, err))
+				continue
+			}
+			if previous := pc.count.Swap(int32(count)); int(previous) != count {
+				pc.logger.Info("partition count changed", slog.String(LogKeyTopic, pc.topic),
+					slog.Int("previous", int(previous)), slog.Int("partitions", count))
+			}
+		case <-pc.stop:
+			return
+		}
+	}
+}
+
+// close останавливает обновление числа партиций
+func (pc *partitionCount) close() {
+	close(pc.stop)
+	<-pc.done
+}
@@ -0,0 +1,59 @@
+package kafka
+
+import "testing"
+
+// Значения из тестов murmur2 Java-клиента Kafka (UtilsTest.testMurmur2)
+var murmur2Vectors = []struct {
+	key  string
+	hash int32
+}{
+	{"21", -973932308},
+	{"foobar", -790332482},
+	{"a-little-bit-long-string", -985981536},
+	{"a-little-bit-longer-string", -1486304829},
+	{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+	{"abc", 479470107},
+}
+
+func TestMurmur2(t *testing.T) {
+	for _, v := range murmur2Vectors {
+		if got := int32(murmur2([]byte(v.key))); got != v.hash {
+			t.Errorf("murmur2(%q) = %d; want %d", v.key, got, v.hash)
+		}
+	}
+}
+
+func TestMurmur2Partitioner(t *testing.T) {
+	// Партиция выбирается как toPositive(murmur2(key)) % partitions
+	tests := []struct {
+		key        string
+		partitions int
+		want       int32
+	}{
+		{"21", 10, 0},
+		{"21", 100, 40},
+		{"foobar", 10, 6},
+		{"foobar", 100, 66},
+		{"a-little-bit-long-string", 3, 2},
+		{"a-little-bit-longer-string", 10, 9},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", 100, 77},
+		{"abc", 10, 7},
+		{"foobar", 1, 0},
+	}
+
+	partitioner := Murmur2Partitioner()
+	for _, tt := range tests {
+		if got := partitioner.Partition("topic", []byte(tt.key), tt.partitions); got != tt.want {
+			t.Errorf("Partition(%q, %d) = %d; want %d", tt.key, tt.partitions, got, tt.want)
+		}
+	}
+}
+
+func TestMurmur2PartitionerWithoutKey(t *testing.T) {
+	partitioner := Murmur2Partitioner()
+	for i := 0; i < 100; i++ {
+		if got := partitioner.Partition("topic", nil, 3); got < 0 || got >= 3 {
+			t.Fatalf("Partition(nil, 3) = %d; want [0, 3)", got)
+		}
+	}
+}
@@ -2,35 +2,66 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync/atomic"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Producer представляет Kafka продюсера
 type Producer struct {
-	producer *kafka.Producer
-	topic    string
-	logger   *log.Logger
+	producer      *kafka.Producer
+	topic         string
+	logger        *slog.Logger
+	tokenProvider TokenProvider
+	metrics       *Metrics
+	tracing       *tracing
+	partitioner   Partitioner
+	partitions    *partitionCount
+	delayBuckets  DelayBuckets
+	// reporting - запущена обработка отчетов о доставке (ProcessDeliveryReports)
+	reporting atomic.Bool
 }
 
-// NewProducer создает новый экземпляр продюсера Kafka
-func NewProducer(topic string, config map[string]string, logger *log.Logger) (*Producer, error) {
+// ErrNoTopic возвращается при отправке без топика продюсером, созданным NewMultiTopicProducer
+var ErrNoTopic = errors.New("message topic is required: producer has no default topic")
+
+// NewProducer создает новый экземпляр продюсера Kafka.
+// Если logger равен nil, используется slog.Default()
+func NewProducer(topic string, config map[string]string, logger *slog.Logger, opts ...Option) (*Producer, error) {
+	if topic == "" {
+		return nil, errors.New("producer topic is required, use NewMultiTopicProducer to send to several topics")
+	}
+	return newProducer(topic, config, logger, newOptions(opts))
+}
+
+// NewMultiTopicProducer создает продюсера без топика по умолчанию: топик указывается
+// в каждом сообщении SendMessage. Partitioner и SendAt требуют топика продюсера и не
+// поддерживаются, а метрика producer_queue_messages не публикуется, так как очередь
+// librdkafka общая для всех топиков
+func NewMultiTopicProducer(config map[string]string, logger *slog.Logger, opts ...Option) (*Producer, error) {
+	o := newOptions(opts)
+	if o.partitioner != nil {
+		return nil, errors.New("partitioner requires a producer topic")
+	}
+	return newProducer("", config, logger, o)
+}
+
+// newProducer создает продюсера; topic пуст у продюсера NewMultiTopicProducer
+func newProducer(topic string, config map[string]string, logger *slog.Logger, o *options) (*Producer, error) {
 	// Создаем базовую конфигурацию
 	defaultConfig := map[string]string{
 		"bootstrap.servers": "kafka:29092",
 	}
 
-	// Объединяем с пользовательской конфигурацией
-	for k, v := range config {
-		defaultConfig[k] = v
-	}
-
-	// Преобразуем map в kafka.ConfigMap
-	configMap := kafka.ConfigMap{}
-	for k, v := range defaultConfig {
-		configMap[k] = v
+	// Объединяем с пользовательской конфигурацией и параметрами безопасности
+	configMap, err := buildConfigMap(defaultConfig, config, o)
+	if err != nil {
+		return nil, err
 	}
 
 	// Создаем продюсера
@@ -39,35 +70,128 @@ func NewProducer(topic string, config map[string]string, logger *log.Logger) (*P
 		return nil, fmt.Errorf("failed to create producer: %w", err)
 	}
 
-	return &Producer{
-		producer: p,
-		topic:    topic,
-		logger:   logger,
-	}, nil
+	logger = loggerOrDefault(logger)
+	if o.librdkafkaLogs {
+		go forwardLibrdkafkaLogs(p.Logs(), logger)
+	}
+
+	// Передаем начальный OAuth-токен, последующие обновления обрабатываются
+	// в ProcessDeliveryReports
+	tokenProvider := o.security.tokenProvider()
+	if tokenProvider != nil {
+		if err := refreshOAuthBearerToken(p, tokenProvider); err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+
+	producer := &Producer{
+		producer:      p,
+		topic:         topic,
+		logger:        logger,
+		tokenProvider: tokenProvider,
+		metrics:       o.metrics,
+		tracing:       o.tracing,
+		partitioner:   o.partitioner,
+		delayBuckets:  o.delayBuckets,
+	}
+
+	// Для выбора партиции на стороне клиента нужно число партиций топика
+	if o.partitioner != nil {
+		producer.partitions, err = newPartitionCount(p, topic, logger)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+
+	return producer, nil
 }
 
 // Send отправляет сообщение в Kafka
 func (p *Producer) Send(value string, key string) error {
-	// Создаем сообщение
-	message := &kafka.Message{
-		TopicPartition: kafka.TopicPartition{
-			Topic:     &p.topic,
-			Partition: kafka.PartitionAny,
-		},
-		Value: []byte(value),
-	}
+	return p.SendWithContext(context.Background(), value, key)
+}
+
+// SendWithContext отправляет сообщение в Kafka; span отправки создается
+// в контексте ctx, а его контекст передается в заголовках сообщения
+func (p *Producer) SendWithContext(ctx context.Context, value string, key string) error {
+	record := Record{Value: []byte(value)}
 
 	// Если ключ указан, добавляем его к сообщению
 	if key != "" {
-		message.Key = []byte(key)
+		record.Key = []byte(key)
+	}
+
+	return p.SendRecord(ctx, record)
+}
+
+// Record - сообщение с ключом, значением и заголовками
+type Record struct {
+	Key     []byte
+	Value   []byte
+	Headers []kafka.Header
+	// Timestamp - временная метка сообщения; если не задана, ее назначает librdkafka
+	Timestamp time.Time
+}
+
+// SendRecord отправляет сообщение с заголовками в Kafka
+func (p *Producer) SendRecord(ctx context.Context, record Record) error {
+	return p.SendMessage(ctx, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Partition: kafka.PartitionAny},
+		Key:            record.Key,
+		Value:          record.Value,
+		Headers:        append([]kafka.Header(nil), record.Headers...),
+		Timestamp:      record.Timestamp,
+	}, nil)
+}
+
+// SendMessage отправляет готовое сообщение. Если топик сообщения не указан, используется
+// топик продюсера; при kafka.PartitionAny партицию выбирает Partitioner или librdkafka.
+// Отчет о доставке передается в deliveryChan, а если он равен nil - в ProcessDeliveryReports.
+// С deliveryChan span отправки завершается при постановке в очередь и не отмечает ошибку доставки
+func (p *Producer) SendMessage(ctx context.Context, message *kafka.Message, deliveryChan chan kafka.Event) error {
+	if message.TopicPartition.Topic == nil {
+		if p.topic == "" {
+			return ErrNoTopic
+		}
+		message.TopicPartition.Topic = &p.topic
+	}
+	topic := *message.TopicPartition.Topic
+
+	// Выбираем партицию, если задан Partitioner; иначе ее выбирает librdkafka
+	if p.partitioner != nil && topic == p.topic && message.TopicPartition.Partition == kafka.PartitionAny {
+		message.TopicPartition.Partition = p.partitioner.Partition(topic, message.Key, p.partitions.get())
 	}
 
-	// Отправляем сообщение
-	if err := p.producer.Produce(message, nil); err != nil {
+	// Начинаем span отправки и передаем контекст трассировки в заголовках
+	span := p.tracing.startProducerSpan(ctx, message)
+
+	// Отчеты без канала доставки обрабатывает ProcessDeliveryReports: к opaque добавляется
+	// время постановки в очередь, от которого отсчитывается задержка доставки, и span,
+	// который завершается по отчету. Produce копирует opaque, поэтому сообщение
+	// вызывающего сразу восстанавливается
+	opaque := message.Opaque
+	var reported trace.Span
+	if deliveryChan == nil {
+		if p.reporting.Load() {
+			reported = span
+		}
+		message.Opaque = &enqueued{opaque: opaque, at: time.Now(), span: reported}
+	}
+	err := p.producer.Produce(message, deliveryChan)
+	message.Opaque = opaque
+	if err != nil {
+		endSpan(span, err)
+		p.metrics.produceFailed(topic)
 		return fmt.Errorf("failed to produce message: %w", err)
 	}
+	if reported == nil {
+		endSpan(span, nil)
+	}
+	p.setQueueSize()
 
-	p.logger.Printf("Отправлено сообщение в топик %s: %s (ключ: %s)", p.topic, value, key)
+	p.logger.Info("message sent", slog.String(LogKeyTopic, topic), slog.String(LogKeyKey, string(message.Key)))
 	return nil
 }
 
@@ -88,7 +212,7 @@ func (p *Producer) SendBatch(messages []string, keys []string) (int, error) {
 		}
 
 		if err := p.Send(msg, key); err != nil {
-			p.logger.Printf("Ошибка при отправке сообщения: %v", err)
+			p.logger.Error("failed to send message", slog.String(LogKeyKey, key), slog.Any(LogKeyError, err))
 			continue
 		}
 		sentCount++
@@ -101,31 +225,87 @@ func (p *Producer) SendBatch(messages []string, keys []string) (int, error) {
 func (p *Producer) Flush() {
 	remainingMessages := p.producer.Flush(15000) // 15 секунд
 	if remainingMessages > 0 {
-		p.logger.Printf("Предупреждение: %d сообщений не были отправлены", remainingMessages)
+		p.logger.Warn("messages not delivered after flush", slog.Int("remaining", remainingMessages))
 	} else {
-		p.logger.Printf("Все сообщения успешно отправлены")
+		p.logger.Info("all messages flushed")
 	}
 }
 
+// setQueueSize обновляет метрику размера очереди продюсера с топиком
+func (p *Producer) setQueueSize() {
+	if p.topic != "" {
+		p.metrics.setQueueSize(p.topic, p.producer.Len())
+	}
+}
+
+// PartitionCount возвращает число партиций топика, известное Partitioner,
+// или 0, если Partitioner не задан
+func (p *Producer) PartitionCount() int {
+	if p.partitions == nil {
+		return 0
+	}
+	return p.partitions.get()
+}
+
 // Close закрывает соединение с Kafka
 func (p *Producer) Close() {
+	if p.partitions != nil {
+		p.partitions.close()
+	}
 	p.producer.Close()
-	p.logger.Printf("Соединение с Kafka закрыто")
+	p.logger.Info("producer closed")
+}
+
+// enqueued - opaque сообщения, отправленного без канала доставки, время его постановки
+// в очередь и span отправки, ожидающий отчета о доставке
+type enqueued struct {
+	opaque interface{}
+	at     time.Time
+	span   trace.Span
 }
 
-// ProcessDeliveryReports запускает обработку отчетов о доставке сообщений
+// ProcessDeliveryReports запускает обработку отчетов о доставке сообщений.
+// Метрики доставки и статистика librdkafka собираются только при запущенной обработке,
+// и только тогда span отправки завершается по отчету и отмечает ошибку доставки
 func (p *Producer) ProcessDeliveryReports(ctx context.Context) {
+	p.reporting.Store(true)
 	go func() {
+		defer p.reporting.Store(false)
 		for {
 			select {
 			case e := <-p.producer.Events():
 				switch ev := e.(type) {
 				case *kafka.Message:
+					// Задержка доставки отсчитывается от постановки сообщения в очередь
+					var enqueuedAt time.Time
+					if e, ok := ev.Opaque.(*enqueued); ok {
+						ev.Opaque = e.opaque
+						enqueuedAt = e.at
+						endSpan(e.span, ev.TopicPartition.Error)
+					}
+					attrs := messageAttrs(ev)
+					if !enqueuedAt.IsZero() {
+						attrs = append(attrs, slog.Duration(LogKeyLatency, time.Since(enqueuedAt)))
+					}
 					if ev.TopicPartition.Error != nil {
-						p.logger.Printf("Ошибка доставки: %v", ev.TopicPartition.Error)
+						p.logger.Error("message delivery failed",
+							append(attrs, slog.Any(LogKeyError, ev.TopicPartition.Error))...)
 					} else {
-						p.logger.Printf("Сообщение доставлено в %s [%d] со смещением %v",
-							*ev.TopicPartition.Topic, ev.TopicPartition.Partition, ev.TopicPartition.Offset)
+						p.logger.Info("message delivered", attrs...)
+					}
+					p.metrics.delivered(ev, enqueuedAt)
+					p.setQueueSize()
+				case *kafka.Stats:
+					if err := p.metrics.observeStats(ev); err != nil {
+						p.logger.Warn("failed to observe statistics", slog.Any(LogKeyError, err))
+					}
+				case kafka.OAuthBearerTokenRefresh:
+					if p.tokenProvider == nil {
+						p.logger.Warn("OAuth token refresh requested but no token provider configured")
+						continue
+					}
+					if err := refreshOAuthBearerToken(p.producer, p.tokenProvider); err != nil {
+						p.logger.Error("failed to refresh OAuth token", slog.Any(LogKeyError, err))
 					}
 				}
 			case <-ctx.Done():
@@ -133,4 +313,4 @@ func (p *Producer) ProcessDeliveryReports(ctx context.Context) {
 			}
 		}
 	}()
-}
\ No newline at end of file
+}
@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// RebalanceHook вызывается при изменении назначения партиций консьюмеру
+type RebalanceHook func(partitions []kafka.TopicPartition)
+
+// WithCooperativeRebalance включает протокол cooperative-sticky: при перебалансировке
+// отзываются и назначаются только изменившиеся партиции, остальные продолжают обрабатываться
+func WithCooperativeRebalance() Option {
+	return func(o *options) {
+		o.cooperativeRebalance = true
+	}
+}
+
+// OnAssigned добавляет обработчик, вызываемый перед началом чтения назначенных партиций.
+// Обработчик может изменить Offset партиций, чтобы начать чтение не с зафиксированной позиции
+func (c *Consumer) OnAssigned(hook RebalanceHook) {
+	c.onAssigned = append(c.onAssigned, hook)
+}
+
+// OnRevoked добавляет обработчик, вызываемый при отзыве партиций после завершения
+// обработки их сообщений и перед фиксацией смещений
+func (c *Consumer) OnRevoked(hook RebalanceHook) {
+	c.onRevoked = append(c.onRevoked, hook)
+}
+
+// OnLost добавляет обработчик, вызываемый при потере партиций (например, после
+// истечения сессии), когда фиксировать смещения уже нельзя
+func (c *Consumer) OnLost(hook RebalanceHook) {
+	c.onLost = append(c.onLost, hook)
+}
+
+// rebalance вызывается librdkafka из Poll при изменении назначения партиций
+func (c *Consumer) rebalance(_ *kafka.Consumer, ev kafka.Event) error {
+	cooperative := c.consumer.GetRebalanceProtocol() == "COOPERATIVE"
+
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions:
+		c.logger.Info("partitions assigned", slog.Any("partitions", e.Partitions))
+		for _, hook := range c.onAssigned {
+			hook(e.Partitions)
+		}
+		return c.assign(e.Partitions, cooperative)
+
+	case kafka.RevokedPartitions:
+		if c.abandoned.Load() {
+			// Соединение закрывается, не дождавшись обработчиков: их сообщения
+			// не освобождаются и смещения не фиксируются
+			c.logger.Warn("partitions released without finishing processing", slog.Any("partitions", e.Partitions))
+			return c.unassign(e.Partitions, cooperative)
+		}
+		if c.consumer.AssignmentLost() {
+			c.logger.Warn("partitions lost", slog.Any("partitions", e.Partitions))
+			c.dropPartitions(e.Partitions)
+			for _, hook := range c.onLost {
+				hook(e.Partitions)
+			}
+		} else {
+			c.logger.Info("partitions revoked", slog.Any("partitions", e.Partitions))
+			c.releasePartitions(e.Partitions)
+			for _, hook := range c.onRevoked {
+				hook(e.Partitions)
+			}
+			// Фиксируем смещения до передачи партиций другому участнику группы
+			c.commitStored()
+		}
+		return c.unassign(e.Partitions, cooperative)
+	}
+
+	return nil
+}
+
+// releasePartitions завершает обработку сообщений отзываемых партиций и фиксирует их смещения
+func (c *Consumer) releasePartitions(partitions []kafka.TopicPartition) {
+	defer c.flow.forget(partitions)
+	defer c.forgetFailures(partitions)
+
+	if workers := c.workers.Load(); workers != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+		defer cancel()
+
+		offsets, unfinished := workers.drain(ctx, partitions)
+		c.commitOffsets(offsets)
+		if len(unfinished) > 0 {
+			// Незавершенные сообщения будут повторно получены новым владельцем партиции
+			c.logger.Warn("revoked partitions have unfinished messages", slog.Any("unfinished", unfinished))
+		}
+	}
+	if c.batcher != nil {
+		c.batcher.flushPartitions(partitions)
+	}
+}
+
+// dropPartitions завершает обработку сообщений потерянных партиций без фиксации смещений
+func (c *Consumer) dropPartitions(partitions []kafka.TopicPartition) {
+	defer c.flow.forget(partitions)
+	defer c.forgetFailures(partitions)
+
+	if workers := c.workers.Load(); workers != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+		defer cancel()
+
+		workers.drain(ctx, partitions)
+	}
+	if c.batcher != nil {
+		c.batcher.dropPartitions(partitions)
+	}
+}
+
+// forgetFailures сбрасывает счетчики ошибок обработки отозванных партиций
+func (c *Consumer) forgetFailures(partitions []kafka.TopicPartition) {
+	for _, tp := range partitions {
+		delete(c.failures, newPartitionKey(tp))
+	}
+}
+
+// assign назначает партиции полностью или инкрементально для cooperative-протокола
+func (c *Consumer) assign(partitions []kafka.TopicPartition, cooperative bool) error {
+	var err error
+	if cooperative {
+		err = c.consumer.IncrementalAssign(partitions)
+	} else {
+		err = c.consumer.Assign(partitions)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to assign partitions: %w", err)
+	}
+	return nil
+}
+
+// unassign снимает назначение партиций полностью или инкрементально для cooperative-протокола
+func (c *Consumer) unassign(partitions []kafka.TopicPartition, cooperative bool) error {
+	var err error
+	if cooperative {
+		err = c.consumer.IncrementalUnassign(partitions)
+	} else {
+		err = c.consumer.Unassign()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unassign partitions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/riferrei/srclient"
+)
+
+// schemaRegistryTimeout - таймаут HTTP-запросов к Schema Registry по умолчанию
+const schemaRegistryTimeout = 10 * time.Second
+
+// SchemaRegistryConfig описывает подключение к Schema Registry
+type SchemaRegistryConfig struct {
+	// URL - адрес Schema Registry
+	URL string
+	// Username и Password используются для basic-аутентификации
+	Username string
+	Password string
+	// TLS - параметры TLS, в том числе клиентский сертификат для mTLS
+	TLS *TLSConfig
+	// Timeout - таймаут HTTP-запросов, по умолчанию 10 секунд
+	Timeout time.Duration
+}
+
+// NewSchemaRegistryClient создает клиент Schema Registry с учетом параметров безопасности
+func NewSchemaRegistryClient(config SchemaRegistryConfig) (*srclient.SchemaRegistryClient, error) {
+	if config.URL == "" {
+		config.URL = "http://schema-registry:8081"
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = schemaRegistryTimeout
+	}
+
+	httpClient := &http.Client{Timeout: timeout}
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.tlsConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema registry TLS config: %w", err)
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	client := srclient.NewSchemaRegistryClient(config.URL, srclient.WithClient(httpClient))
+	if config.Username != "" {
+		client.SetCredentials(config.Username, config.Password)
+	}
+
+	return client, nil
+}
+
+// tlsConfig преобразует параметры TLS в конфигурацию crypto/tls
+func (t *TLSConfig) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CALocation != "" {
+		caCert, err := os.ReadFile(t.CALocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CALocation)
+		}
+		config.RootCAs = pool
+	}
+
+	if t.CertLocation != "" || t.KeyLocation != "" {
+		if t.KeyPassword != "" {
+			return nil, fmt.Errorf("encrypted client keys are not supported")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertLocation, t.KeyLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,188 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Протоколы безопасности (security.protocol)
+const (
+	ProtocolPlaintext     = "PLAINTEXT"
+	ProtocolSSL           = "SSL"
+	ProtocolSASLPlaintext = "SASL_PLAINTEXT"
+	ProtocolSASLSSL       = "SASL_SSL"
+)
+
+// Механизмы SASL (sasl.mechanism)
+const (
+	MechanismPlain       = "PLAIN"
+	MechanismScramSHA256 = "SCRAM-SHA-256"
+	MechanismScramSHA512 = "SCRAM-SHA-512"
+	MechanismOAuthBearer = "OAUTHBEARER"
+)
+
+// tokenRefreshTimeout - максимальное время получения нового OAuth-токена
+const tokenRefreshTimeout = 30 * time.Second
+
+// TokenProvider возвращает OAuth-токен для механизма SASL/OAUTHBEARER
+type TokenProvider func(ctx context.Context) (kafka.OAuthBearerToken, error)
+
+// SecurityConfig описывает параметры безопасности подключения к брокерам
+type SecurityConfig struct {
+	// Protocol - протокол безопасности (PLAINTEXT, SSL, SASL_PLAINTEXT, SASL_SSL)
+	Protocol string
+	// SASL - параметры аутентификации SASL, обязательны для протоколов SASL_*
+	SASL *SASLConfig
+	// TLS - параметры TLS, в том числе клиентский сертификат для mTLS
+	TLS *TLSConfig
+}
+
+// SASLConfig описывает параметры аутентификации SASL
+type SASLConfig struct {
+	// Mechanism - механизм SASL (PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, OAUTHBEARER)
+	Mechanism string
+	// Username и Password используются механизмами PLAIN и SCRAM
+	Username string
+	Password string
+	// TokenProvider выдает токены для OAUTHBEARER; токен обновляется по запросу librdkafka
+	TokenProvider TokenProvider
+}
+
+// TLSConfig описывает параметры TLS-соединения
+type TLSConfig struct {
+	// CALocation - путь к сертификату удостоверяющего центра
+	CALocation string
+	// CertLocation и KeyLocation - клиентский сертификат и ключ для mTLS
+	CertLocation string
+	KeyLocation  string
+	// KeyPassword - пароль закрытого ключа, если он зашифрован
+	KeyPassword string
+	// InsecureSkipVerify отключает проверку сертификата брокера
+	InsecureSkipVerify bool
+}
+
+// ConfigMap преобразует параметры безопасности в свойства librdkafka
+func (s *SecurityConfig) ConfigMap() (map[string]string, error) {
+	config := map[string]string{}
+
+	protocol := s.Protocol
+	if protocol == "" {
+		protocol = ProtocolPlaintext
+	}
+	config["security.protocol"] = protocol
+
+	switch protocol {
+	case ProtocolPlaintext, ProtocolSSL:
+		if s.SASL != nil {
+			return nil, fmt.Errorf("SASL settings require %s or %s protocol", ProtocolSASLPlaintext, ProtocolSASLSSL)
+		}
+	case ProtocolSASLPlaintext, ProtocolSASLSSL:
+		if s.SASL == nil {
+			return nil, fmt.Errorf("protocol %s requires SASL settings", protocol)
+		}
+		saslConfig, err := s.SASL.configMap()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range saslConfig {
+			config[k] = v
+		}
+	default:
+		return nil, fmt.Errorf("unsupported security protocol: %s", protocol)
+	}
+
+	if s.TLS != nil {
+		if protocol != ProtocolSSL && protocol != ProtocolSASLSSL {
+			return nil, fmt.Errorf("TLS settings require %s or %s protocol", ProtocolSSL, ProtocolSASLSSL)
+		}
+		for k, v := range s.TLS.configMap() {
+			config[k] = v
+		}
+	}
+
+	return config, nil
+}
+
+// tokenProvider возвращает провайдер OAuth-токенов, если он настроен
+func (s *SecurityConfig) tokenProvider() TokenProvider {
+	if s == nil || s.SASL == nil || s.SASL.Mechanism != MechanismOAuthBearer {
+		return nil
+	}
+	return s.SASL.TokenProvider
+}
+
+// configMap преобразует параметры SASL в свойства librdkafka
+func (s *SASLConfig) configMap() (map[string]string, error) {
+	config := map[string]string{
+		"sasl.mechanism": s.Mechanism,
+	}
+
+	switch s.Mechanism {
+	case MechanismPlain, MechanismScramSHA256, MechanismScramSHA512:
+		if s.Username == "" {
+			return nil, fmt.Errorf("mechanism %s requires username", s.Mechanism)
+		}
+		config["sasl.username"] = s.Username
+		config["sasl.password"] = s.Password
+	case MechanismOAuthBearer:
+		if s.TokenProvider == nil {
+			return nil, fmt.Errorf("mechanism %s requires token provider", s.Mechanism)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism: %s", s.Mechanism)
+	}
+
+	return config, nil
+}
+
+// configMap преобразует параметры TLS в свойства librdkafka
+func (t *TLSConfig) configMap() map[string]string {
+	config := map[string]string{}
+
+	if t.CALocation != "" {
+		config["ssl.ca.location"] = t.CALocation
+	}
+	if t.CertLocation != "" {
+		config["ssl.certificate.location"] = t.CertLocation
+	}
+	if t.KeyLocation != "" {
+		config["ssl.key.location"] = t.KeyLocation
+	}
+	if t.KeyPassword != "" {
+		config["ssl.key.password"] = t.KeyPassword
+	}
+	if t.InsecureSkipVerify {
+		config["enable.ssl.certificate.verification"] = "false"
+		config["ssl.endpoint.identification.algorithm"] = "none"
+	}
+
+	return config
+}
+
+// oauthBearerClient - клиент librdkafka, принимающий OAuth-токены
+type oauthBearerClient interface {
+	SetOAuthBearerToken(token kafka.OAuthBearerToken) error
+	SetOAuthBearerTokenFailure(errstr string) error
+}
+
+// refreshOAuthBearerToken запрашивает новый токен у провайдера и передает его клиенту
+func refreshOAuthBearerToken(client oauthBearerClient, provider TokenProvider) error {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+	defer cancel()
+
+	token, err := provider(ctx)
+	if err != nil {
+		client.SetOAuthBearerTokenFailure(err.Error())
+		return fmt.Errorf("failed to obtain OAuth token: %w", err)
+	}
+
+	if err := client.SetOAuthBearerToken(token); err != nil {
+		client.SetOAuthBearerTokenFailure(err.Error())
+		return fmt.Errorf("failed to set OAuth token: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+const (
+	// defaultShutdownTimeout - время ожидания обработки полученных сообщений при остановке
+	defaultShutdownTimeout = 30 * time.Second
+	// closeGracePeriod - запас времени Close на завершение цикла после ожидания обработчиков
+	closeGracePeriod = 5 * time.Second
+)
+
+// WithStaticMembership включает статическое членство в группе (group.instance.id).
+// Если instanceID пуст, используется имя пода из переменной POD_NAME или имя хоста.
+// Участник, перезапущенный в пределах session.timeout.ms, получает прежние партиции
+// без перебалансировки группы; при закрытии он не покидает группу явно
+func WithStaticMembership(instanceID string) Option {
+	return func(o *options) {
+		o.staticMembership = true
+		o.groupInstanceID = instanceID
+	}
+}
+
+// WithShutdownTimeout задает, сколько ждать обработки полученных сообщений
+// при остановке консьюмера и при отзыве партиций; по умолчанию 30 секунд
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = timeout
+	}
+}
+
+// instanceID возвращает идентификатор статического участника группы
+func (o *options) instanceID() (string, error) {
+	if o.groupInstanceID != "" {
+		return o.groupInstanceID, nil
+	}
+	if podName := os.Getenv("POD_NAME"); podName != "" {
+		return podName, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine group instance id: %w", err)
+	}
+	return hostname, nil
+}
+
+// UnfinishedPartition описывает сообщения партиции, обработка которых не завершилась
+type UnfinishedPartition struct {
+	// Partition - партиция; Offset - первое незавершенное сообщение,
+	// с которого чтение продолжится после перезапуска
+	Partition kafka.TopicPartition
+	// Messages - количество незавершенных сообщений
+	Messages int
+}
+
+// ShutdownReport - результат остановки консьюмера
+type ShutdownReport struct {
+	// Unfinished - партиции с сообщениями, не обработанными до истечения времени остановки;
+	// их смещения не зафиксированы, и сообщения будут получены повторно
+	Unfinished []UnfinishedPartition
+}
+
+// Shutdown останавливает консьюмера: прекращает получение сообщений, ждет завершения
+// обработки полученных сообщений до истечения ctx, фиксирует итоговые смещения
+// и покидает группу. Если цикл обработки не завершился до истечения ctx, соединение
+// все равно закрывается без фиксации смещений обрабатываемых сообщений: они перечислены
+// в отчете и будут получены повторно; возвращается ошибка
+func (c *Consumer) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	c.Stop()
+
+	c.loopMu.Lock()
+	done := c.loopDone
+	c.loopMu.Unlock()
+
+	var report ShutdownReport
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			report.Unfinished = c.inflight()
+			c.abandoned.Store(true)
+			if err := c.closeConsumer(); err != nil {
+				c.logger.Error("failed to close consumer", slog.Any(LogKeyError, err))
+			}
+			c.logger.Warn("consumer closed before processing finished", slog.Any("unfinished", report.Unfinished))
+			return report, fmt.Errorf("consumer did not stop in time: %w", ctx.Err())
+		}
+	}
+
+	c.loopMu.Lock()
+	report.Unfinished = c.unfinished
+	c.loopMu.Unlock()
+
+	// Фиксируем смещения, сохраненные после последней фиксации
+	c.commitStored()
+
+	// Close отзывает партиции и покидает группу (кроме статических участников)
+	if err := c.closeConsumer(); err != nil {
+		return report, fmt.Errorf("failed to close consumer: %w", err)
+	}
+
+	if len(report.Unfinished) > 0 {
+		c.logger.Warn("consumer closed with unfinished messages", slog.Any("unfinished", report.Unfinished))
+	} else {
+		c.logger.Info("consumer closed")
+	}
+	return report, nil
+}
+
+// closeConsumer закрывает соединение; цикл обработки, который еще не завершился,
+// больше не вызывает Poll
+func (c *Consumer) closeConsumer() error {
+	c.pollMu.Lock()
+	defer c.pollMu.Unlock()
+
+	return c.consumer.Close()
+}
+
+// inflight возвращает сообщения, обработка которых еще идет: незавершенные сообщения
+// пула обработчиков, текущий пакет или текущее сообщение
+func (c *Consumer) inflight() []UnfinishedPartition {
+	if workers := c.workers.Load(); workers != nil {
+		return workers.pending()
+	}
+
+	var msgs []*kafka.Message
+	if batch := c.currentBatch.Load(); batch != nil {
+		msgs = *batch
+	} else if msg := c.current.Load(); msg != nil {
+		msgs = []*kafka.Message{msg}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	counts := make(map[partitionKey]int)
+	for _, msg := range msgs {
+		counts[newPartitionKey(msg.TopicPartition)]++
+	}
+	first := batchPositions(msgs, func(next, current kafka.Offset) bool { return next < current })
+	unfinished := make([]UnfinishedPartition, 0, len(first))
+	for _, tp := range first {
+		unfinished = append(unfinished, UnfinishedPartition{Partition: tp, Messages: counts[newPartitionKey(tp)]})
+	}
+	return unfinished
+}
+
+// Close останавливает консьюмера, ожидая обработки полученных сообщений
+// не дольше времени остановки, и закрывает соединение с Kafka
+func (c *Consumer) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout+closeGracePeriod)
+	defer cancel()
+
+	if _, err := c.Shutdown(ctx); err != nil {
+		c.logger.Error("failed to shut down consumer", slog.Any(LogKeyError, err))
+	}
+}
+
+// enterLoop отмечает начало цикла обработки; возвращаемая функция отмечает его завершение
+func (c *Consumer) enterLoop() func() {
+	done := make(chan struct{})
+
+	c.loopMu.Lock()
+	c.loopDone = done
+	c.unfinished = nil
+	c.loopMu.Unlock()
+
+	return func() { close(done) }
+}
+
+// setUnfinished запоминает незавершенные при остановке сообщения для Shutdown
+func (c *Consumer) setUnfinished(unfinished []UnfinishedPartition) {
+	c.loopMu.Lock()
+	defer c.loopMu.Unlock()
+
+	c.unfinished = unfinished
+}
@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName - имя инструментирующей библиотеки в трассировках
+const tracerName = "github.com/kafka-examples/golang/src/kafka"
+
+// messagingOperationProcess - операция обработки сообщения из более поздних версий
+// семантических соглашений; в v1.24.0 для нее еще нет константы
+var messagingOperationProcess = semconv.MessagingOperationKey.String("process")
+
+// tracing хранит трейсер и пропагатор клиента
+type tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracing включает трассировку OpenTelemetry. Если provider равен nil,
+// используется глобальный TracerProvider; если propagator равен nil -
+// W3C Trace Context (заголовки traceparent и tracestate)
+func WithTracing(provider trace.TracerProvider, propagator propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		if provider == nil {
+			provider = otel.GetTracerProvider()
+		}
+		if propagator == nil {
+			propagator = propagation.TraceContext{}
+		}
+		o.tracing = &tracing{
+			tracer:     provider.Tracer(tracerName),
+			propagator: propagator,
+		}
+	}
+}
+
+// startProducerSpan начинает span отправки и записывает контекст трассировки в заголовки сообщения
+func (t *tracing) startProducerSpan(ctx context.Context, msg *kafka.Message) trace.Span {
+	if t == nil {
+		return nil
+	}
+
+	topic := topicName(msg)
+	ctx, span := t.tracer.Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingMessageBodySize(len(msg.Value)),
+		),
+	)
+	if len(msg.Key) > 0 {
+		span.SetAttributes(semconv.MessagingKafkaMessageKey(string(msg.Key)))
+	}
+
+	t.propagator.Inject(ctx, &headerCarrier{msg: msg})
+	return span
+}
+
+// startConsumerSpan извлекает контекст трассировки из заголовков и начинает span обработки,
+// связанный со span отправителя
+func (t *tracing) startConsumerSpan(ctx context.Context, msg *kafka.Message, group string) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	topic := topicName(msg)
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKafka,
+		messagingOperationProcess,
+		semconv.MessagingDestinationName(topic),
+		semconv.MessagingKafkaDestinationPartition(int(msg.TopicPartition.Partition)),
+		semconv.MessagingKafkaMessageOffset(int(msg.TopicPartition.Offset)),
+		semconv.MessagingMessageBodySize(len(msg.Value)),
+	}
+	if group != "" {
+		attrs = append(attrs, semconv.MessagingKafkaConsumerGroup(group))
+	}
+	if len(msg.Key) > 0 {
+		attrs = append(attrs, semconv.MessagingKafkaMessageKey(string(msg.Key)))
+	}
+
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	}
+	remote := trace.SpanContextFromContext(t.propagator.Extract(ctx, &headerCarrier{msg: msg}))
+	if remote.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: remote}))
+	}
+
+	return t.tracer.Start(ctx, topic+" process", opts...)
+}
+
+// endSpan завершает span, отмечая ошибку, если она есть
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// headerCarrier адаптирует заголовки сообщения Kafka к propagation.TextMapCarrier
+type headerCarrier struct {
+	msg *kafka.Message
+}
+
+// Get возвращает значение заголовка по ключу
+func (c *headerCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set заменяет значение заголовка или добавляет новый заголовок
+func (c *headerCarrier) Set(key string, value string) {
+	for i, h := range c.msg.Headers {
+		if h.Key == key {
+			c.msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys возвращает ключи всех заголовков
+func (c *headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.Headers))
+	for _, h := range c.msg.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
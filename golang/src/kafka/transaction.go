@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// InitTransactions подготавливает продюсера с transactional.id к транзакциям и завершает
+// незавершенные транзакции предыдущего экземпляра с тем же transactional.id
+func (p *Producer) InitTransactions(ctx context.Context) error {
+	if err := p.producer.InitTransactions(ctx); err != nil {
+		return fmt.Errorf("failed to init transactions: %w", err)
+	}
+	return nil
+}
+
+// BeginTransaction начинает транзакцию: сообщения, отправленные до CommitTransaction,
+// и смещения SendOffsetsToTransaction фиксируются атомарно. Консьюмеры
+// с isolation.level=read_committed (значение librdkafka по умолчанию) не видят
+// сообщений незафиксированной или отмененной транзакции
+func (p *Producer) BeginTransaction() error {
+	if err := p.producer.BeginTransaction(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return nil
+}
+
+// SendOffsetsToTransaction добавляет в транзакцию позиции группы консьюмера consumer:
+// они фиксируются только вместе с транзакцией. Консьюмер должен быть создан
+// с WithoutOffsetCommit, чтобы не фиксировать смещения самостоятельно
+func (p *Producer) SendOffsetsToTransaction(ctx context.Context, offsets []kafka.TopicPartition, consumer *Consumer) error {
+	metadata, err := consumer.consumer.GetConsumerGroupMetadata()
+	if err != nil {
+		return fmt.Errorf("failed to get consumer group metadata: %w", err)
+	}
+	if err := p.producer.SendOffsetsToTransaction(ctx, offsets, metadata); err != nil {
+		return fmt.Errorf("failed to send offsets to transaction: %w", err)
+	}
+	return nil
+}
+
+// CommitTransaction дожидается доставки сообщений транзакции и фиксирует ее
+func (p *Producer) CommitTransaction(ctx context.Context) error {
+	if err := p.producer.CommitTransaction(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AbortTransaction отменяет транзакцию: ее сообщения не будут видны консьюмерам
+// с isolation.level=read_committed, а позиции группы не изменятся
+func (p *Producer) AbortTransaction(ctx context.Context) error {
+	if err := p.producer.AbortTransaction(ctx); err != nil {
+		return fmt.Errorf("failed to abort transaction: %w", err)
+	}
+	return nil
+}
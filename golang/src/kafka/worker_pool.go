@@ -0,0 +1,310 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// WithPartitionWorkers включает параллельную обработку: каждая назначенная партиция
+// обрабатывается отдельной горутиной с очередью на queueSize сообщений.
+// Порядок внутри партиции сохраняется, смещения сохраняются только до последнего
+// непрерывно обработанного сообщения. Сообщение, обработка которого завершилась ошибкой,
+// обрабатывается повторно с растущей паузой, задерживая следующие сообщения своей очереди
+func WithPartitionWorkers(queueSize int) Option {
+	return func(o *options) {
+		if queueSize < 1 {
+			queueSize = 1
+		}
+		o.workerQueueSize = queueSize
+		o.workersPerPartition = 1
+	}
+}
+
+// WithKeyOrderedWorkers включает параллельную обработку внутри партиции: сообщения
+// каждой партиции распределяются по workers горутинам по хешу ключа.
+// Порядок сохраняется для каждого ключа, а не для партиции целиком; сообщения без ключа
+// распределяются по горутинам равномерно. Смещения сохраняются только до последнего
+// непрерывно обработанного сообщения, даже если более поздние сообщения завершились раньше
+func WithKeyOrderedWorkers(workers int, queueSize int) Option {
+	return func(o *options) {
+		if workers < 1 {
+			workers = 1
+		}
+		if queueSize < 1 {
+			queueSize = 1
+		}
+		o.workerQueueSize = queueSize
+		o.workersPerPartition = workers
+	}
+}
+
+// partitionKey идентифицирует партицию топика
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// newPartitionKey создает ключ партиции из kafka.TopicPartition
+func newPartitionKey(tp kafka.TopicPartition) partitionKey {
+	key := partitionKey{partition: tp.Partition}
+	if tp.Topic != nil {
+		key.topic = *tp.Topic
+	}
+	return key
+}
+
+// topicPartition преобразует ключ обратно в kafka.TopicPartition
+func (k partitionKey) topicPartition(offset kafka.Offset) kafka.TopicPartition {
+	topic := k.topic
+	return kafka.TopicPartition{Topic: &topic, Partition: k.partition, Offset: offset}
+}
+
+// partitionWorker обрабатывает сообщения одной партиции в одной или нескольких очередях
+type partitionWorker struct {
+	key     partitionKey
+	lanes   []*workerLane
+	tracker *offsetTracker
+	// storeMu упорядочивает сохранение позиций, вычисленных разными очередями
+	storeMu sync.Mutex
+	// stopping закрывается при остановке обработчика партиции и прерывает повторы
+	stopping chan struct{}
+}
+
+// workerLane - очередь и горутина, обрабатывающие сообщения по порядку
+type workerLane struct {
+	queue chan *kafka.Message
+	done  chan struct{}
+}
+
+// lane выбирает очередь для сообщения: по хешу ключа или по смещению, если ключа нет
+func (w *partitionWorker) lane(msg *kafka.Message) *workerLane {
+	if len(w.lanes) == 1 {
+		return w.lanes[0]
+	}
+	if len(msg.Key) == 0 {
+		return w.lanes[uint64(msg.TopicPartition.Offset)%uint64(len(w.lanes))]
+	}
+	h := fnv.New32a()
+	h.Write(msg.Key)
+	return w.lanes[h.Sum32()%uint32(len(w.lanes))]
+}
+
+// workerPool распределяет сообщения по обработчикам партиций
+type workerPool struct {
+	consumer  *Consumer
+	handler   Handler
+	ctx       context.Context
+	cancel    context.CancelFunc
+	queueSize int
+	lanes     int
+	// highWater и lowWater - пороги backpressure; 0 - backpressure выключен
+	highWater int
+	lowWater  int
+
+	mu      sync.Mutex
+	workers map[partitionKey]*partitionWorker
+}
+
+// newWorkerPool создает пул с lanes очередями на партицию;
+// обработчики запускаются при поступлении первого сообщения партиции.
+// Отмена ctx не прерывает обработку уже полученных сообщений: их контекст
+// отменяется только по истечении времени остановки пула
+func newWorkerPool(ctx context.Context, consumer *Consumer, handler Handler, queueSize int, lanes int, highWater int, lowWater int) *workerPool {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	return &workerPool{
+		consumer:  consumer,
+		handler:   handler,
+		ctx:       ctx,
+		cancel:    cancel,
+		queueSize: queueSize,
+		lanes:     lanes,
+		highWater: highWater,
+		lowWater:  lowWater,
+		workers:   make(map[partitionKey]*partitionWorker),
+	}
+}
+
+// dispatch ставит сообщение в очередь обработчика партиции;
+// блокируется, если очередь заполнена
+func (p *workerPool) dispatch(msg *kafka.Message) {
+	w := p.worker(newPartitionKey(msg.TopicPartition))
+	w.tracker.add(msg.TopicPartition.Offset)
+	p.adjustFlow(w)
+	w.lane(msg).queue <- msg
+}
+
+// adjustFlow приостанавливает или возобновляет партицию по заполненности ее очереди
+func (p *workerPool) adjustFlow(w *partitionWorker) {
+	if p.highWater > 0 {
+		p.consumer.flow.adjust(p.consumer, w.key, w.tracker.size, p.highWater, p.lowWater)
+	}
+}
+
+// worker возвращает обработчик партиции, запуская его при необходимости
+func (p *workerPool) worker(key partitionKey) *partitionWorker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if w, ok := p.workers[key]; ok {
+		return w
+	}
+
+	w := &partitionWorker{
+		key:      key,
+		lanes:    make([]*workerLane, p.lanes),
+		tracker:  newOffsetTracker(),
+		stopping: make(chan struct{}),
+	}
+	for i := range w.lanes {
+		w.lanes[i] = &workerLane{
+			queue: make(chan *kafka.Message, p.queueSize),
+			done:  make(chan struct{}),
+		}
+		go p.run(w, w.lanes[i])
+	}
+	p.workers[key] = w
+	return w
+}
+
+// run обрабатывает сообщения очереди до ее закрытия
+func (p *workerPool) run(w *partitionWorker, lane *workerLane) {
+	defer close(lane.done)
+
+	for msg := range lane.queue {
+		if !p.handle(w, msg) {
+			// Обработчик партиции остановлен до успешной обработки: сообщение и следующие
+			// за ним в очереди не входят в фиксируемый префикс и будут получены повторно
+			return
+		}
+
+		// Сохраняем смещение, только если непрерывный префикс обработанных сообщений вырос
+		w.storeMu.Lock()
+		if position, advanced := w.tracker.done(msg.TopicPartition.Offset); advanced {
+			p.consumer.storeOffset(w.key.topicPartition(position))
+		}
+		w.storeMu.Unlock()
+
+		p.adjustFlow(w)
+	}
+}
+
+// handle обрабатывает сообщение, повторяя обработку после ошибок с растущей паузой.
+// Возвращает false, если обработчик партиции остановлен до успешной обработки
+func (p *workerPool) handle(w *partitionWorker, msg *kafka.Message) bool {
+	for failures := 1; ; failures++ {
+		err := p.handler(p.ctx, msg)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, ErrStopConsuming) {
+			p.consumer.Stop()
+			return true
+		}
+		p.consumer.logger.Error("failed to handle message",
+			append(messageAttrs(msg), slog.Int("attempt", failures), slog.Any(LogKeyError, err))...)
+		if !sleep(retryDelay(failures), w.stopping) {
+			return false
+		}
+	}
+}
+
+// drain дожидается обработки поставленных в очередь сообщений указанных партиций
+// (не дольше, чем до отмены ctx), останавливает их обработчики и возвращает позиции
+// для фиксации и незавершенные сообщения
+func (p *workerPool) drain(ctx context.Context, partitions []kafka.TopicPartition) ([]kafka.TopicPartition, []UnfinishedPartition) {
+	p.mu.Lock()
+	var workers []*partitionWorker
+	for _, tp := range partitions {
+		if w, ok := p.workers[newPartitionKey(tp)]; ok {
+			workers = append(workers, w)
+		}
+	}
+	p.mu.Unlock()
+
+	return p.stop(ctx, workers)
+}
+
+// close дожидается обработки всех сообщений (не дольше, чем до отмены ctx)
+// и останавливает обработчики; незавершенные обработчики получают отмену контекста
+func (p *workerPool) close(ctx context.Context) ([]kafka.TopicPartition, []UnfinishedPartition) {
+	p.mu.Lock()
+	workers := make([]*partitionWorker, 0, len(p.workers))
+	for _, w := range p.workers {
+		workers = append(workers, w)
+	}
+	p.mu.Unlock()
+
+	defer p.cancel()
+	return p.stop(ctx, workers)
+}
+
+// stop закрывает очереди обработчиков и ждет их завершения до отмены ctx.
+// Обработчики удаляются из пула после ожидания, чтобы pending видел их сообщения
+func (p *workerPool) stop(ctx context.Context, workers []*partitionWorker) ([]kafka.TopicPartition, []UnfinishedPartition) {
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, w := range workers {
+			delete(p.workers, w.key)
+		}
+	}()
+
+	for _, w := range workers {
+		close(w.stopping)
+		for _, lane := range w.lanes {
+			close(lane.queue)
+		}
+	}
+
+	var offsets []kafka.TopicPartition
+	for _, w := range workers {
+		w.wait(ctx)
+		if position, ok := w.tracker.committable(); ok {
+			offsets = append(offsets, w.key.topicPartition(position))
+		}
+	}
+	return offsets, unfinishedOf(workers)
+}
+
+// pending возвращает незавершенные сообщения всех обработчиков пула,
+// в том числе останавливаемых
+func (p *workerPool) pending() []UnfinishedPartition {
+	p.mu.Lock()
+	workers := make([]*partitionWorker, 0, len(p.workers))
+	for _, w := range p.workers {
+		workers = append(workers, w)
+	}
+	p.mu.Unlock()
+
+	return unfinishedOf(workers)
+}
+
+// unfinishedOf возвращает первые незавершенные сообщения обработчиков и их число
+func unfinishedOf(workers []*partitionWorker) []UnfinishedPartition {
+	var unfinished []UnfinishedPartition
+	for _, w := range workers {
+		if offset, count := w.tracker.pending(); count > 0 {
+			unfinished = append(unfinished, UnfinishedPartition{
+				Partition: w.key.topicPartition(offset),
+				Messages:  count,
+			})
+		}
+	}
+	return unfinished
+}
+
+// wait ждет завершения всех очередей обработчика партиции или отмены ctx
+func (w *partitionWorker) wait(ctx context.Context) {
+	for _, lane := range w.lanes {
+		select {
+		case <-lane.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/kafka-examples/golang/src/admin"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// loadTimeout - максимальное время чтения топика контрольных точек при запуске
+const loadTimeout = time.Minute
+
+// Checkpoint связывает позицию в исходной партиции с позицией в целевой
+type Checkpoint struct {
+	SourceTopic string `json:"source_topic"`
+	TargetTopic string `json:"target_topic"`
+	Partition   int32  `json:"partition"`
+	// SourceOffset - смещение следующего сообщения исходной партиции, которое нужно скопировать
+	SourceOffset int64 `json:"source_offset"`
+	// TargetOffset - смещение, которое получит это сообщение в целевой партиции
+	TargetOffset int64     `json:"target_offset"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// partitionKey идентифицирует исходную партицию
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// checkpointKey возвращает ключ контрольной точки в компактируемом топике;
+// имя зеркала в ключе позволяет нескольким зеркалам использовать один топик
+func checkpointKey(name string, key partitionKey) string {
+	return fmt.Sprintf("%s/%s/%d", name, key.topic, key.partition)
+}
+
+// ensureCheckpointTopic создает компактируемый топик контрольных точек, если его нет
+func ensureCheckpointTopic(ctx context.Context, target *admin.Client, topic string, replicationFactor int) error {
+	_, err := target.EnsureTopics(ctx, []admin.TopicSpec{{
+		Name:              topic,
+		Partitions:        1,
+		ReplicationFactor: replicationFactor,
+		CleanupPolicy:     "compact",
+	}})
+	return err
+}
+
+// loadCheckpoints читает топик контрольных точек целиком и возвращает
+// последние контрольные точки зеркала name по каждой исходной партиции
+func loadCheckpoints(ctx context.Context, name string, topic string, target *admin.Client,
+	config map[string]string, logger *slog.Logger, opts []kafkalib.Option) (map[partitionKey]Checkpoint, error) {
+	descriptions, err := target.DescribeTopics(ctx, []string{topic})
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]kafka.TopicPartition, descriptions[0].Partitions)
+	for i := range partitions {
+		partitions[i] = kafka.TopicPartition{Topic: &topic, Partition: int32(i), Offset: kafka.OffsetBeginning}
+	}
+
+	consumerConfig := make(map[string]string, len(config)+1)
+	for k, v := range config {
+		consumerConfig[k] = v
+	}
+	consumerConfig["group.id"] = name + "-checkpoints"
+
+	// Топик контрольных точек каждый раз читается с начала, смещения группы не фиксируются
+	consumer, err := kafkalib.NewConsumer(nil, consumerConfig, logger,
+		append(append([]kafkalib.Option(nil), opts...), kafkalib.WithAssignment(partitions), kafkalib.WithoutOffsetCommit())...)
+	if err != nil {
+		return nil, err
+	}
+	defer consumer.Close()
+
+	// Читаем до верхней границы каждой непустой партиции на момент запуска
+	remaining := make(map[int32]int64)
+	for _, tp := range partitions {
+		low, high, err := consumer.WatermarkOffsets(topic, tp.Partition)
+		if err != nil {
+			return nil, err
+		}
+		if high > low {
+			remaining[tp.Partition] = high
+		}
+	}
+
+	checkpoints := make(map[partitionKey]Checkpoint)
+	if len(remaining) == 0 {
+		return checkpoints, nil
+	}
+
+	prefix := name + "/"
+	ctx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+
+	err = consumer.Run(ctx, func(_ context.Context, msg *kafka.Message) error {
+		if strings.HasPrefix(string(msg.Key), prefix) && msg.Value != nil {
+			var cp Checkpoint
+			if err := json.Unmarshal(msg.Value, &cp); err != nil {
+				logger.Warn("invalid checkpoint", slog.String(kafkalib.LogKeyKey, string(msg.Key)), slog.Any(kafkalib.LogKeyError, err))
+			} else {
+				checkpoints[partitionKey{cp.SourceTopic, cp.Partition}] = cp
+			}
+		}
+
+		p := msg.TopicPartition.Partition
+		if int64(msg.TopicPartition.Offset)+1 >= remaining[p] {
+			delete(remaining, p)
+		}
+		if len(remaining) == 0 {
+			return kafkalib.ErrStopConsuming
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("failed to load checkpoints from %s: timeout", topic)
+		}
+		return nil, fmt.Errorf("failed to load checkpoints from %s: %w", topic, err)
+	}
+
+	logger.Info("checkpoints loaded", slog.String(kafkalib.LogKeyTopic, topic), slog.Int("count", len(checkpoints)))
+	return checkpoints, nil
+}
@@ -0,0 +1,391 @@
+// Package mirror копирует топики из одного кластера Kafka в другой с сохранением
+// ключей, заголовков, временных меток и партиций. Соответствие смещений исходных
+// и целевых партиций сохраняется в компактируемом топике целевого кластера
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/kafka-examples/golang/src/admin"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// Значения по умолчанию
+const (
+	defaultCheckpointTopic = "mirror-checkpoints"
+	defaultBatchSize       = 500
+	defaultBatchWait       = time.Second
+	syncTimeout            = 30 * time.Second
+)
+
+// Config - параметры зеркала
+type Config struct {
+	// Name - имя зеркала: группа консьюмеров в исходном кластере и префикс ключей контрольных точек
+	Name string
+	// Topics - регулярное выражение для выбора топиков исходного кластера
+	Topics string
+	// Source и Target - параметры librdkafka исходного и целевого кластеров
+	Source map[string]string
+	Target map[string]string
+	// SourceOptions и TargetOptions - опции клиентов исходного и целевого кластеров
+	// (безопасность, метрики)
+	SourceOptions []kafkalib.Option
+	TargetOptions []kafkalib.Option
+	// TopicPrefix - префикс имен топиков в целевом кластере
+	TopicPrefix string
+	// CheckpointTopic - компактируемый топик контрольных точек в целевом кластере
+	CheckpointTopic string
+	// ReplicationFactor - фактор репликации создаваемых топиков; 0 - значение брокера
+	ReplicationFactor int
+	// BatchSize и BatchWait ограничивают пакет, после доставки которого сохраняется контрольная точка
+	BatchSize int
+	BatchWait time.Duration
+}
+
+// Mirror копирует сообщения выбранных топиков в целевой кластер
+type Mirror struct {
+	config   Config
+	logger   *slog.Logger
+	consumer *kafkalib.Consumer
+	producer *kafkalib.Producer
+	source   *admin.Client
+	target   *admin.Client
+
+	mu          sync.Mutex
+	checkpoints map[partitionKey]Checkpoint
+	// partitions - число партиций целевых топиков, приведенных в соответствие с исходными
+	partitions map[string]int
+}
+
+// New создает зеркало: подготавливает топик контрольных точек, загружает из него
+// последние позиции и подписывается на топики исходного кластера.
+// Если logger равен nil, используется slog.Default()
+func New(config Config, logger *slog.Logger) (*Mirror, error) {
+	if config.Name == "" || config.Topics == "" {
+		return nil, errors.New("mirror requires name and topics")
+	}
+	if config.CheckpointTopic == "" {
+		config.CheckpointTopic = defaultCheckpointTopic
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.BatchWait == 0 {
+		config.BatchWait = defaultBatchWait
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With(slog.String("mirror", config.Name))
+
+	m := &Mirror{
+		config:      config,
+		logger:      logger,
+		checkpoints: make(map[partitionKey]Checkpoint),
+		partitions:  make(map[string]int),
+	}
+	if err := m.open(); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// open создает клиентов обоих кластеров и загружает контрольные точки
+func (m *Mirror) open() error {
+	var err error
+	m.source, err = admin.NewClient(m.config.Source, m.logger, m.config.SourceOptions...)
+	if err != nil {
+		return err
+	}
+	m.target, err = admin.NewClient(m.config.Target, m.logger, m.config.TargetOptions...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	if err := ensureCheckpointTopic(ctx, m.target, m.config.CheckpointTopic, m.config.ReplicationFactor); err != nil {
+		return err
+	}
+	m.checkpoints, err = loadCheckpoints(context.Background(), m.config.Name, m.config.CheckpointTopic,
+		m.target, m.config.Target, m.logger, m.config.TargetOptions)
+	if err != nil {
+		return err
+	}
+
+	// Идемпотентный продюсер сохраняет порядок сообщений в партиции при повторных отправках
+	producerConfig := map[string]string{"enable.idempotence": "true"}
+	for k, v := range m.config.Target {
+		producerConfig[k] = v
+	}
+	m.producer, err = kafkalib.NewProducer(m.config.CheckpointTopic, producerConfig, m.logger, m.config.TargetOptions...)
+	if err != nil {
+		return err
+	}
+
+	// Служебные топики (__consumer_offsets, _schemas) не копируются
+	consumerConfig := map[string]string{
+		"auto.offset.reset": "earliest",
+		"topic.blacklist":   "^_.*",
+	}
+	for k, v := range m.config.Source {
+		consumerConfig[k] = v
+	}
+	consumerConfig["group.id"] = m.config.Name
+
+	pattern := m.config.Topics
+	if !strings.HasPrefix(pattern, "^") {
+		pattern = "^" + pattern
+	}
+	m.consumer, err = kafkalib.NewConsumer([]string{pattern}, consumerConfig, m.logger, m.config.SourceOptions...)
+	if err != nil {
+		return err
+	}
+	m.consumer.OnAssigned(m.resume)
+	return nil
+}
+
+// Run копирует сообщения до отмены ctx. Смещения в исходном кластере фиксируются
+// только после доставки пакета и сохранения его контрольных точек
+func (m *Mirror) Run(ctx context.Context) error {
+	return m.consumer.RunBatch(ctx, m.mirrorBatch, m.config.BatchSize, m.config.BatchWait)
+}
+
+// Close останавливает зеркало и закрывает клиентов
+func (m *Mirror) Close() {
+	if m.consumer != nil {
+		m.consumer.Close()
+	}
+	if m.producer != nil {
+		m.producer.Close()
+	}
+	if m.source != nil {
+		m.source.Close()
+	}
+	if m.target != nil {
+		m.target.Close()
+	}
+}
+
+// Checkpoint возвращает последнюю контрольную точку исходной партиции
+func (m *Mirror) Checkpoint(topic string, partition int32) (Checkpoint, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cp, ok := m.checkpoints[partitionKey{topic, partition}]
+	return cp, ok
+}
+
+// Translate переводит смещение исходной партиции в смещение целевой, например при
+// переключении консьюмеров на целевой кластер. Перевод консервативен: сообщения
+// могут быть прочитаны повторно, но не пропущены. Возвращает false, если для партиции
+// нет контрольной точки
+func (m *Mirror) Translate(topic string, partition int32, sourceOffset int64) (int64, bool) {
+	cp, ok := m.Checkpoint(topic, partition)
+	if !ok {
+		return 0, false
+	}
+	if sourceOffset >= cp.SourceOffset {
+		return cp.TargetOffset, true
+	}
+	// Между смещениями могли быть удаленные при компактировании сообщения,
+	// поэтому целевая позиция не может отставать больше, чем на разницу смещений
+	return max(cp.TargetOffset-(cp.SourceOffset-sourceOffset), 0), true
+}
+
+// resume начинает чтение назначенных партиций с последних контрольных точек, которые
+// могут опережать зафиксированные смещения группы после аварийной остановки
+func (m *Mirror) resume(partitions []kafka.TopicPartition) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, tp := range partitions {
+		cp, ok := m.checkpoints[partitionKey{*tp.Topic, tp.Partition}]
+		if ok && cp.TargetTopic == m.targetTopic(*tp.Topic) {
+			partitions[i].Offset = kafka.Offset(cp.SourceOffset)
+		}
+	}
+}
+
+// targetTopic возвращает имя топика в целевом кластере
+func (m *Mirror) targetTopic(topic string) string {
+	return m.config.TopicPrefix + topic
+}
+
+// mirrorBatch копирует пакет, дожидается его доставки и сохраняет контрольные точки
+func (m *Mirror) mirrorBatch(ctx context.Context, msgs []*kafka.Message) error {
+	if err := m.syncTopics(ctx, msgs); err != nil {
+		return err
+	}
+
+	deliveries := make(chan kafka.Event, len(msgs))
+	for _, msg := range msgs {
+		target := m.targetTopic(*msg.TopicPartition.Topic)
+		copied := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &target, Partition: msg.TopicPartition.Partition},
+			Key:            msg.Key,
+			Value:          msg.Value,
+			Headers:        append([]kafka.Header(nil), msg.Headers...),
+			// Исходное сообщение нужно, чтобы связать смещения в контрольной точке
+			Opaque: msg,
+		}
+		if msg.TimestampType != kafka.TimestampNotAvailable {
+			copied.Timestamp = msg.Timestamp
+		}
+		if err := m.producer.SendMessage(ctx, copied, deliveries); err != nil {
+			return err
+		}
+	}
+
+	checkpoints, err := m.awaitDeliveries(ctx, deliveries, len(msgs))
+	if err != nil {
+		return err
+	}
+	return m.saveCheckpoints(ctx, checkpoints)
+}
+
+// awaitDeliveries дожидается отчетов о доставке и возвращает контрольные точки
+// по последнему доставленному сообщению каждой партиции
+func (m *Mirror) awaitDeliveries(ctx context.Context, deliveries chan kafka.Event, count int) (map[partitionKey]Checkpoint, error) {
+	checkpoints := make(map[partitionKey]Checkpoint)
+	var errs []error
+	for i := 0; i < count; i++ {
+		var ev kafka.Event
+		select {
+		case ev = <-deliveries:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		delivered, ok := ev.(*kafka.Message)
+		if !ok {
+			continue
+		}
+		if delivered.TopicPartition.Error != nil {
+			errs = append(errs, delivered.TopicPartition.Error)
+			continue
+		}
+
+		source := delivered.Opaque.(*kafka.Message)
+		key := partitionKey{*source.TopicPartition.Topic, source.TopicPartition.Partition}
+		sourceOffset := int64(source.TopicPartition.Offset) + 1
+		if cp, ok := checkpoints[key]; ok && cp.SourceOffset >= sourceOffset {
+			continue
+		}
+		checkpoints[key] = Checkpoint{
+			SourceTopic:  key.topic,
+			TargetTopic:  *delivered.TopicPartition.Topic,
+			Partition:    key.partition,
+			SourceOffset: sourceOffset,
+			TargetOffset: int64(delivered.TopicPartition.Offset) + 1,
+			Timestamp:    time.Now(),
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("failed to deliver %d of %d messages: %w", len(errs), count, err)
+	}
+	return checkpoints, nil
+}
+
+// saveCheckpoints записывает контрольные точки и дожидается их доставки
+func (m *Mirror) saveCheckpoints(ctx context.Context, checkpoints map[partitionKey]Checkpoint) error {
+	deliveries := make(chan kafka.Event, len(checkpoints))
+	for key, cp := range checkpoints {
+		value, err := json.Marshal(cp)
+		if err != nil {
+			return fmt.Errorf("failed to encode checkpoint: %w", err)
+		}
+		message := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Partition: kafka.PartitionAny},
+			Key:            []byte(checkpointKey(m.config.Name, key)),
+			Value:          value,
+		}
+		if err := m.producer.SendMessage(ctx, message, deliveries); err != nil {
+			return err
+		}
+	}
+
+	for range checkpoints {
+		select {
+		case ev := <-deliveries:
+			if delivered, ok := ev.(*kafka.Message); ok && delivered.TopicPartition.Error != nil {
+				return fmt.Errorf("failed to save checkpoint: %w", delivered.TopicPartition.Error)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	m.mu.Lock()
+	for key, cp := range checkpoints {
+		m.checkpoints[key] = cp
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// syncTopics создает целевые топики и добавляет в них партиции, чтобы
+// каждая исходная партиция копировалась в партицию с тем же номером
+func (m *Mirror) syncTopics(ctx context.Context, msgs []*kafka.Message) error {
+	for _, msg := range msgs {
+		topic := *msg.TopicPartition.Topic
+		if int(msg.TopicPartition.Partition) < m.partitions[topic] {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+		partitions, err := m.syncTopic(ctx, topic)
+		cancel()
+		if err != nil {
+			return err
+		}
+		m.partitions[topic] = partitions
+	}
+	return nil
+}
+
+// syncTopic приводит целевой топик к исходному и возвращает число его партиций.
+// Параметры исходного топика копируются только при создании
+func (m *Mirror) syncTopic(ctx context.Context, topic string) (int, error) {
+	source, err := m.source.DescribeTopics(ctx, []string{topic})
+	if err != nil {
+		return 0, err
+	}
+	spec := admin.TopicSpec{
+		Name:              m.targetTopic(topic),
+		Partitions:        source[0].Partitions,
+		ReplicationFactor: m.config.ReplicationFactor,
+		Config:            source[0].Config,
+	}
+
+	target, err := m.target.DescribeTopics(ctx, []string{spec.Name})
+	switch {
+	case errors.Is(err, admin.ErrTopicNotFound):
+		err = m.target.CreateTopics(ctx, []admin.TopicSpec{spec})
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrTopicAlreadyExists {
+			err = nil
+		}
+	case err != nil:
+	case target[0].Partitions < spec.Partitions:
+		err = m.target.CreatePartitions(ctx, spec.Name, spec.Partitions)
+	default:
+		return target[0].Partitions, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	m.logger.Info("target topic synced", slog.String(kafkalib.LogKeyTopic, spec.Name), slog.Int("partitions", spec.Partitions))
+	return spec.Partitions, nil
+}
@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect описывает различия SQL между базами данных
+type Dialect struct {
+	name string
+	// placeholder возвращает параметр запроса с номером n (начиная с 1)
+	placeholder func(n int) string
+	// claimLock - запрос, упорядочивающий закрепление строк экземплярами Relay в пределах
+	// транзакции; параметр - ключ блокировки таблицы. Пусто, если база сама выполняет
+	// записывающие транзакции последовательно
+	claimLock string
+	// idColumn - определение автоинкрементного первичного ключа
+	idColumn string
+	// blob - тип двоичных данных
+	blob string
+}
+
+// Postgres - диалект PostgreSQL; строки закрепляются под транзакционной advisory-блокировкой,
+// поэтому каждый экземпляр Relay видит строки, закрепленные остальными
+var Postgres = Dialect{
+	name:        "postgres",
+	placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+	claimLock:   "SELECT pg_advisory_xact_lock($1)",
+	idColumn:    "id BIGSERIAL PRIMARY KEY",
+	blob:        "BYTEA",
+}
+
+// SQLite - диалект SQLite для локальной разработки и тестов;
+// записи в SQLite выполняются последовательно, поэтому блокировка не нужна
+var SQLite = Dialect{
+	name:        "sqlite",
+	placeholder: func(int) string { return "?" },
+	idColumn:    "id INTEGER PRIMARY KEY AUTOINCREMENT",
+	blob:        "BLOB",
+}
+
+// String возвращает имя диалекта
+func (d Dialect) String() string {
+	return d.name
+}
+
+// placeholders возвращает список параметров с номерами от first до first+count-1
+func (d Dialect) placeholders(first int, count int) string {
+	params := make([]string, count)
+	for i := range params {
+		params[i] = d.placeholder(first + i)
+	}
+	return strings.Join(params, ", ")
+}
@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// Inbox - таблица идентификаторов обработанных сообщений (шаблон transactional inbox)
+// для дедупликации на стороне консьюмера. Реализует kafka.DedupStore; middleware
+// Deduplicate записывает идентификатор в одной транзакции с изменениями обработчика
+type Inbox struct {
+	db      *sql.DB
+	name    string
+	dialect Dialect
+}
+
+// txKey - ключ транзакции обработчика в контексте
+type txKey struct{}
+
+// NewInbox создает Inbox для таблицы table (по умолчанию inbox)
+func NewInbox(db *sql.DB, dialect Dialect, table string) (*Inbox, error) {
+	if table == "" {
+		table = "inbox"
+	}
+	name, err := Table{Name: table, Dialect: dialect}.name()
+	if err != nil {
+		return nil, err
+	}
+	return &Inbox{db: db, name: name, dialect: dialect}, nil
+}
+
+// Create создает таблицу, если ее нет
+func (i *Inbox) Create(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	message_id TEXT PRIMARY KEY,
+	processed_at TIMESTAMP NOT NULL
+)`, i.name)
+	if _, err := i.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create inbox table %s: %w", i.name, err)
+	}
+	return nil
+}
+
+// Seen сообщает, записан ли идентификатор
+func (i *Inbox) Seen(ctx context.Context, id string) (bool, error) {
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE message_id = %s", i.name, i.dialect.placeholder(1))
+	var found int
+	err := i.db.QueryRowContext(ctx, query, id).Scan(&found)
+	switch {
+	case err == sql.ErrNoRows:
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to check inbox: %w", err)
+	}
+	return true, nil
+}
+
+// Mark записывает идентификатор
+func (i *Inbox) Mark(ctx context.Context, id string) error {
+	_, err := i.insert(ctx, i.db, id)
+	return err
+}
+
+// insert записывает идентификатор и сообщает, что его еще не было
+func (i *Inbox) insert(ctx context.Context, exec Execer, id string) (bool, error) {
+	query := fmt.Sprintf("INSERT INTO %s (message_id, processed_at) VALUES (%s) ON CONFLICT (message_id) DO NOTHING",
+		i.name, i.dialect.placeholders(1, 2))
+	result, err := exec.ExecContext(ctx, query, id, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to record message in inbox: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record message in inbox: %w", err)
+	}
+	return inserted > 0, nil
+}
+
+// Deduplicate пропускает уже обработанные сообщения. Обработчик выполняется в транзакции,
+// в которой записан идентификатор сообщения: транзакция доступна через TxFromContext
+// и фиксируется только при успешной обработке, поэтому изменения обработчика в базе
+// и отметка об обработке сохраняются атомарно. В Postgres параллельная обработка
+// того же сообщения другим консьюмером ждет завершения транзакции и затем пропускается.
+// Сообщения с пустым идентификатором обрабатываются без транзакции
+func (i *Inbox) Deduplicate(id kafkalib.MessageIDFunc) kafkalib.Middleware {
+	return func(next kafkalib.Handler) kafkalib.Handler {
+		return func(ctx context.Context, msg *kafka.Message) error {
+			messageID := id(msg)
+			if messageID == "" {
+				return next(ctx, msg)
+			}
+
+			tx, err := i.db.BeginTx(ctx, nil)
+			if err != nil {
+				return fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			defer tx.Rollback()
+
+			inserted, err := i.insert(ctx, tx, messageID)
+			if err != nil || !inserted {
+				return err
+			}
+
+			if err := next(context.WithValue(ctx, txKey{}, tx), msg); err != nil {
+				return err
+			}
+			if err := tx.Commit(); err != nil {
+				return fmt.Errorf("failed to commit inbox transaction: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// TxFromContext возвращает транзакцию, открытую Inbox.Deduplicate для обработчика
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// Purge удаляет идентификаторы, записанные раньше чем olderThan назад, и возвращает их число.
+// Период хранения должен превышать время, в течение которого возможны повторы
+func (i *Inbox) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE processed_at < %s", i.name, i.dialect.placeholder(1))
+	result, err := i.db.ExecContext(ctx, query, time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge inbox: %w", err)
+	}
+	return result.RowsAffected()
+}
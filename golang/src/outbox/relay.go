@@ -0,0 +1,394 @@
+// Package outbox реализует шаблон transactional outbox: сервис записывает события
+// в таблицу outbox в той же транзакции, что и бизнес-данные, а Relay публикует
+// их в Kafka и помечает отправленными только после подтверждения доставки
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// IDHeader - заголовок с идентификатором строки outbox; позволяет консьюмерам
+// отбрасывать повторы, возможные при сбое между доставкой и отметкой строки
+const IDHeader = "outbox-id"
+
+// Значения по умолчанию
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	defaultLease        = time.Minute
+)
+
+// Config - параметры Relay
+type Config struct {
+	Table Table
+	// BatchSize - максимальное число строк, отправляемых за одну транзакцию
+	BatchSize int
+	// PollInterval - пауза между опросами таблицы, если неотправленных строк нет
+	PollInterval time.Duration
+	// Lease - время, на которое строки пакета закрепляются за экземпляром Relay на время
+	// отправки; по истечении строки может выбрать другой экземпляр. По умолчанию минута
+	Lease time.Duration
+}
+
+// Relay публикует строки таблицы outbox в Kafka
+type Relay struct {
+	db       *sql.DB
+	producer *kafkalib.Producer
+	config   Config
+	logger   *slog.Logger
+	// wake прерывает паузу между опросами
+	wake chan struct{}
+}
+
+// NewRelay создает Relay. Продюсер создается с enable.idempotence=true, чтобы повторные
+// отправки не создавали дубликатов и не нарушали порядок сообщений в партиции.
+// Если logger равен nil, используется slog.Default()
+func NewRelay(db *sql.DB, config Config, producerConfig map[string]string, logger *slog.Logger, opts ...kafkalib.Option) (*Relay, error) {
+	if _, err := config.Table.name(); err != nil {
+		return nil, err
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.Lease <= 0 {
+		config.Lease = defaultLease
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	producerConfigMap := make(map[string]string, len(producerConfig)+1)
+	for k, v := range producerConfig {
+		producerConfigMap[k] = v
+	}
+	producerConfigMap["enable.idempotence"] = "true"
+
+	producer, err := kafkalib.NewMultiTopicProducer(producerConfigMap, logger, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Relay{
+		db:       db,
+		producer: producer,
+		config:   config,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+	}, nil
+}
+
+// Run публикует строки до отмены ctx. Пока в таблице есть неотправленные строки,
+// пакеты отправляются без паузы
+func (r *Relay) Run(ctx context.Context) error {
+	r.logger.Info("outbox relay started", slog.String("dialect", r.config.Table.Dialect.String()))
+
+	for ctx.Err() == nil {
+		sent, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error("failed to relay outbox messages", slog.Any(kafkalib.LogKeyError, err))
+		}
+		if sent == r.config.BatchSize && err == nil {
+			continue
+		}
+
+		timer := time.NewTimer(r.config.PollInterval)
+		select {
+		case <-timer.C:
+		case <-r.wake:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	return ctx.Err()
+}
+
+// Notify прерывает паузу между опросами, например сразу после фиксации транзакции с событием
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close закрывает продюсера
+func (r *Relay) Close() {
+	r.producer.Close()
+}
+
+// row - неотправленная строка outbox
+type row struct {
+	id      int64
+	topic   string
+	key     []byte
+	value   []byte
+	headers sql.NullString
+}
+
+// RelayOnce отправляет один пакет строк и возвращает число строк, помеченных отправленными.
+// Строки выбираются и закрепляются за экземпляром Relay (leased_until) в короткой транзакции,
+// которая не удерживает блокировки во время отправки. Строка не выбирается, пока более
+// ранняя строка с тем же топиком и ключом закреплена за другим экземпляром, поэтому события
+// одного ключа отправляются по порядку. После доставки отправленными помечаются строки
+// до первой неудачной; она и следующие за ней строки освобождаются для повторной отправки
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	rows, err := r.claim(ctx)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	// Ожидание доставки не дольше срока закрепления строк
+	publishCtx, cancel := context.WithTimeout(ctx, r.config.Lease)
+	delivered, deliveryErr := r.publish(publishCtx, rows)
+	cancel()
+
+	ids := make([]int64, len(rows))
+	for i, rw := range rows {
+		ids[i] = rw.id
+	}
+	if err := r.finish(ctx, ids[:delivered], ids[delivered:]); err != nil {
+		return 0, errors.Join(deliveryErr, err)
+	}
+
+	if delivered > 0 {
+		r.logger.Info("outbox messages relayed", slog.Int("count", delivered))
+	}
+	return delivered, deliveryErr
+}
+
+// claim выбирает неотправленные строки и закрепляет их за экземпляром Relay на время Lease.
+// Экземпляры закрепляют строки по очереди, чтобы выборка учитывала закрепленные другими строки
+func (r *Relay) claim(ctx context.Context) ([]row, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if lock := r.config.Table.Dialect.claimLock; lock != "" {
+		if _, err := tx.ExecContext(ctx, lock, r.lockKey()); err != nil {
+			return nil, fmt.Errorf("failed to lock outbox table: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	rows, err := r.selectUnsent(ctx, tx, now)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	ids := make([]int64, len(rows))
+	for i, rw := range rows {
+		ids[i] = rw.id
+	}
+	if err := r.setLease(ctx, tx, ids, now.Add(r.config.Lease)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit outbox lease: %w", err)
+	}
+	return rows, nil
+}
+
+// finish помечает доставленные строки отправленными и освобождает остальные
+func (r *Relay) finish(ctx context.Context, sent []int64, released []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.markSent(ctx, tx, sent); err != nil {
+		return err
+	}
+	if err := r.setLease(ctx, tx, released, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sent outbox messages: %w", err)
+	}
+	return nil
+}
+
+// lockKey возвращает ключ advisory-блокировки таблицы
+func (r *Relay) lockKey() int64 {
+	name, _ := r.config.Table.name()
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// selectUnsent выбирает неотправленные и не закрепленные строки в порядке записи,
+// пропуская строки, перед которыми есть закрепленная строка с тем же топиком и ключом
+func (r *Relay) selectUnsent(ctx context.Context, tx *sql.Tx, now time.Time) ([]row, error) {
+	name, _ := r.config.Table.name()
+	dialect := r.config.Table.Dialect
+	query := fmt.Sprintf("SELECT o.id, o.topic, o.message_key, o.payload, o.headers FROM %[1]s o "+
+		"WHERE o.sent_at IS NULL AND (o.leased_until IS NULL OR o.leased_until < %[2]s) "+
+		"AND NOT EXISTS (SELECT 1 FROM %[1]s e WHERE e.sent_at IS NULL AND e.topic = o.topic "+
+		"AND e.message_key = o.message_key AND e.id < o.id AND e.leased_until >= %[3]s) "+
+		"ORDER BY o.id LIMIT %[4]d",
+		name, dialect.placeholder(1), dialect.placeholder(2), r.config.BatchSize)
+
+	result, err := tx.QueryContext(ctx, query, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select outbox messages: %w", err)
+	}
+	defer result.Close()
+
+	var rows []row
+	for result.Next() {
+		var rw row
+		if err := result.Scan(&rw.id, &rw.topic, &rw.key, &rw.value, &rw.headers); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		rows = append(rows, rw)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select outbox messages: %w", err)
+	}
+	return rows, nil
+}
+
+// publish отправляет строки по порядку и возвращает число строк, доставленных подряд
+// с начала пакета. Отправка прекращается на первой строке, которую не удалось отправить
+func (r *Relay) publish(ctx context.Context, rows []row) (int, error) {
+	deliveries := make(chan kafka.Event, len(rows))
+	var errs []error
+	sent := 0
+	for _, rw := range rows {
+		message, err := rw.message()
+		if err == nil {
+			if err = r.producer.SendMessage(ctx, message, deliveries); err != nil {
+				err = fmt.Errorf("failed to send outbox message %d: %w", rw.id, err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		sent++
+	}
+
+	// Доставка в разные партиции завершается в произвольном порядке
+	delivered := make(map[int64]bool, sent)
+wait:
+	for i := 0; i < sent; i++ {
+		select {
+		case ev := <-deliveries:
+			msg, ok := ev.(*kafka.Message)
+			if !ok {
+				continue
+			}
+			if msg.TopicPartition.Error != nil {
+				errs = append(errs, fmt.Errorf("failed to deliver outbox message %d: %w", msg.Opaque.(int64), msg.TopicPartition.Error))
+				continue
+			}
+			delivered[msg.Opaque.(int64)] = true
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+			break wait
+		}
+	}
+
+	prefix := 0
+	for prefix < len(rows) && delivered[rows[prefix].id] {
+		prefix++
+	}
+	if err := errors.Join(errs...); err != nil {
+		return prefix, fmt.Errorf("failed to relay outbox messages: %w", err)
+	}
+	return prefix, nil
+}
+
+// message преобразует строку в сообщение Kafka
+func (rw row) message() (*kafka.Message, error) {
+	topic := rw.topic
+	message := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            rw.key,
+		Value:          rw.value,
+		Opaque:         rw.id,
+	}
+
+	if rw.headers.Valid && rw.headers.String != "" {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(rw.headers.String), &headers); err != nil {
+			return nil, fmt.Errorf("invalid headers of outbox message %d: %w", rw.id, err)
+		}
+		for k, v := range headers {
+			message.Headers = append(message.Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+	}
+	message.Headers = append(message.Headers, kafka.Header{Key: IDHeader, Value: []byte(strconv.FormatInt(rw.id, 10))})
+	return message, nil
+}
+
+// markSent помечает строки отправленными
+func (r *Relay) markSent(ctx context.Context, tx *sql.Tx, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	name, _ := r.config.Table.name()
+	dialect := r.config.Table.Dialect
+	query := fmt.Sprintf("UPDATE %s SET sent_at = %s, leased_until = NULL WHERE id IN (%s)",
+		name, dialect.placeholder(1), dialect.placeholders(2, len(ids)))
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, time.Now().UTC())
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to mark outbox messages as sent: %w", err)
+	}
+	return nil
+}
+
+// setLease закрепляет строки до until; nil освобождает их
+func (r *Relay) setLease(ctx context.Context, tx *sql.Tx, ids []int64, until any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	name, _ := r.config.Table.name()
+	dialect := r.config.Table.Dialect
+	query := fmt.Sprintf("UPDATE %s SET leased_until = %s WHERE id IN (%s)",
+		name, dialect.placeholder(1), dialect.placeholders(2, len(ids)))
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, until)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update outbox lease: %w", err)
+	}
+	return nil
+}
+
+// DeleteSent удаляет строки, отправленные раньше чем olderThan назад, и возвращает их число
+func (r *Relay) DeleteSent(ctx context.Context, olderThan time.Duration) (int64, error) {
+	name, _ := r.config.Table.name()
+	query := fmt.Sprintf("DELETE FROM %s WHERE sent_at IS NOT NULL AND sent_at < %s",
+		name, r.config.Table.Dialect.placeholder(1))
+
+	result, err := r.db.ExecContext(ctx, query, time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent outbox messages: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,107 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tableName - допустимое имя таблицы (с необязательной схемой)
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Table - таблица outbox в базе данных
+type Table struct {
+	// Name - имя таблицы; по умолчанию outbox
+	Name    string
+	Dialect Dialect
+}
+
+// Message - событие, записываемое в outbox вместе с бизнес-данными
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// Execer выполняет запросы; подходят *sql.DB, *sql.Tx и *sql.Conn
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// name возвращает проверенное имя таблицы
+func (t Table) name() (string, error) {
+	if t.Dialect.placeholder == nil {
+		return "", fmt.Errorf("outbox table requires dialect")
+	}
+	name := t.Name
+	if name == "" {
+		name = "outbox"
+	}
+	if !tableName.MatchString(name) {
+		return "", fmt.Errorf("invalid outbox table name %q", name)
+	}
+	return name, nil
+}
+
+// Create создает таблицу и индексы неотправленных строк, если их нет.
+// Столбец leased_until хранит срок закрепления строки за экземпляром Relay
+func (t Table) Create(ctx context.Context, db Execer) error {
+	name, err := t.name()
+	if err != nil {
+		return err
+	}
+
+	index := strings.ReplaceAll(name, ".", "_") + "_unsent"
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s,
+	topic TEXT NOT NULL,
+	message_key %s,
+	payload %s,
+	headers TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	sent_at TIMESTAMP,
+	leased_until TIMESTAMP
+)`, name, t.Dialect.idColumn, t.Dialect.blob, t.Dialect.blob),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (id) WHERE sent_at IS NULL", index, name),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_key ON %s (topic, message_key, id) WHERE sent_at IS NULL", index, name),
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create outbox table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Insert записывает событие в outbox; exec обычно транзакция, в которой
+// изменяются бизнес-данные, чтобы событие и данные сохранялись атомарно
+func (t Table) Insert(ctx context.Context, exec Execer, msg Message) error {
+	name, err := t.name()
+	if err != nil {
+		return err
+	}
+	if msg.Topic == "" {
+		return fmt.Errorf("outbox message requires topic")
+	}
+
+	var headers sql.NullString
+	if len(msg.Headers) > 0 {
+		encoded, err := json.Marshal(msg.Headers)
+		if err != nil {
+			return fmt.Errorf("failed to encode headers: %w", err)
+		}
+		headers = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (topic, message_key, payload, headers) VALUES (%s)",
+		name, t.Dialect.placeholders(1, 4))
+	if _, err := exec.ExecContext(ctx, query, msg.Topic, msg.Key, msg.Value, headers); err != nil {
+		return fmt.Errorf("failed to insert outbox message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+// Package rpc реализует запрос-ответ поверх Kafka: Requester отправляет запрос
+// с заголовками correlation-id и reply-to и ждет ответ в своей партиции топика ответов,
+// а Responder обрабатывает запросы и отправляет ответы по адресу из заголовков
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/kafka-examples/golang/src/admin"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// Заголовки запросов и ответов
+const (
+	// CorrelationIDHeader - идентификатор запроса, копируется в ответ
+	CorrelationIDHeader = "correlation-id"
+	// ReplyToHeader - топик, в который отправляется ответ
+	ReplyToHeader = "reply-to"
+	// ReplyPartitionHeader - партиция топика ответов
+	ReplyPartitionHeader = "reply-partition"
+	// DeadlineHeader - время (Unix, мс), после которого ответ уже не нужен
+	DeadlineHeader = "request-deadline"
+	// ErrorHeader - текст ошибки обработки запроса; значение ответа в этом случае пусто
+	ErrorHeader = "reply-error"
+)
+
+// Значения по умолчанию
+const (
+	defaultTimeout = 30 * time.Second
+	// offsetsTimeout - время ожидания конца партиции ответов при создании Requester
+	offsetsTimeout = 10 * time.Second
+)
+
+// ErrTimeout возвращается, если ответ не получен до истечения таймаута запроса
+var ErrTimeout = errors.New("request timed out")
+
+// ReplyError - ошибка, которую вернул обработчик запроса на стороне Responder
+type ReplyError struct {
+	Message string
+}
+
+func (e *ReplyError) Error() string {
+	return "remote error: " + e.Message
+}
+
+// RequesterConfig - параметры Requester
+type RequesterConfig struct {
+	// ReplyTopic и ReplyPartition - партиция, из которой экземпляр читает ответы.
+	// Каждому экземпляру сервиса нужна своя партиция или свой топик
+	ReplyTopic     string
+	ReplyPartition int32
+	// Timeout - время ожидания ответа, если у контекста запроса нет дедлайна
+	Timeout time.Duration
+}
+
+// Requester отправляет запросы и сопоставляет с ними ответы по correlation-id
+type Requester struct {
+	config   RequesterConfig
+	producer *kafkalib.Producer
+	consumer *kafkalib.Consumer
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]chan *kafka.Message
+
+	// cancel останавливает чтение ответов, done закрывается после его завершения
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRequester создает Requester и начинает чтение ответов с конца партиции ответов:
+// ответы, отправленные до создания, не читаются. Если logger равен nil, используется slog.Default()
+func NewRequester(config RequesterConfig, clientConfig map[string]string, logger *slog.Logger, opts ...kafkalib.Option) (*Requester, error) {
+	if config.ReplyTopic == "" {
+		return nil, errors.New("reply topic is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	offset, err := endOffset(config, clientConfig, logger, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	consumerConfig := make(map[string]string, len(clientConfig)+1)
+	for k, v := range clientConfig {
+		consumerConfig[k] = v
+	}
+	// group.id обязателен для клиента, но группа не используется: партиция назначается
+	// вручную, а позиция ответов не фиксируется, так как после перезапуска старые ответы не нужны
+	if consumerConfig["group.id"] == "" {
+		consumerConfig["group.id"] = fmt.Sprintf("%s-%d-requester", config.ReplyTopic, config.ReplyPartition)
+	}
+
+	assignment := []kafka.TopicPartition{{Topic: &config.ReplyTopic, Partition: config.ReplyPartition, Offset: kafka.Offset(offset)}}
+	consumer, err := kafkalib.NewConsumer(nil, consumerConfig, logger,
+		append(append([]kafkalib.Option(nil), opts...), kafkalib.WithAssignment(assignment), kafkalib.WithoutOffsetCommit())...)
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := kafkalib.NewMultiTopicProducer(clientConfig, logger, opts...)
+	if err != nil {
+		consumer.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Requester{
+		config:   config,
+		producer: producer,
+		consumer: consumer,
+		logger:   logger,
+		pending:  make(map[string]chan *kafka.Message),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(r.done)
+		r.consumer.Run(ctx, r.handleReply)
+	}()
+	return r, nil
+}
+
+// endOffset возвращает конец партиции ответов. Чтение начинается с явного смещения,
+// а не с kafka.OffsetEnd, чтобы не пропустить ответы на запросы, отправленные
+// до того, как librdkafka определит конец партиции
+func endOffset(config RequesterConfig, clientConfig map[string]string, logger *slog.Logger, opts []kafkalib.Option) (int64, error) {
+	client, err := admin.NewClient(clientConfig, logger, opts...)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), offsetsTimeout)
+	defer cancel()
+
+	offsets, err := client.PartitionOffsets(ctx, config.ReplyTopic)
+	if err != nil {
+		return 0, err
+	}
+	for _, o := range offsets {
+		if o.Partition == config.ReplyPartition {
+			return o.Latest, nil
+		}
+	}
+	return 0, fmt.Errorf("reply topic %s has no partition %d", config.ReplyTopic, config.ReplyPartition)
+}
+
+// Request отправляет запрос в topic и ждет ответ. Если у ctx нет дедлайна,
+// ожидание ограничено Timeout; по его истечении возвращается ErrTimeout.
+// Ошибка обработчика Responder возвращается как *ReplyError
+func (r *Requester) Request(ctx context.Context, topic string, record kafkalib.Record) (*kafka.Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.config.Timeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+
+	id, err := correlationID()
+	if err != nil {
+		return nil, err
+	}
+
+	// Ответ может прийти раньше отчета о доставке запроса, поэтому ожидание
+	// регистрируется до отправки
+	replies := make(chan *kafka.Message, 1)
+	r.mu.Lock()
+	r.pending[id] = replies
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+	}()
+
+	headers := append([]kafka.Header(nil), record.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: CorrelationIDHeader, Value: []byte(id)},
+		kafka.Header{Key: ReplyToHeader, Value: []byte(r.config.ReplyTopic)},
+		kafka.Header{Key: ReplyPartitionHeader, Value: []byte(strconv.Itoa(int(r.config.ReplyPartition)))},
+		kafka.Header{Key: DeadlineHeader, Value: []byte(strconv.FormatInt(deadline.UnixMilli(), 10))},
+	)
+
+	deliveries := make(chan kafka.Event, 1)
+	err = r.producer.SendMessage(ctx, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            record.Key,
+		Value:          record.Value,
+		Headers:        headers,
+		Timestamp:      record.Timestamp,
+	}, deliveries)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case e := <-deliveries:
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				return nil, fmt.Errorf("failed to deliver request: %w", m.TopicPartition.Error)
+			}
+		case reply := <-replies:
+			if message := kafkalib.HeaderID(ErrorHeader)(reply); message != "" {
+				return nil, &ReplyError{Message: message}
+			}
+			return reply, nil
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w: no reply to request %s", ErrTimeout, id)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// handleReply передает ответ ожидающему запросу. Ответы на завершенные по таймауту
+// запросы и ответы другим экземплярам отбрасываются
+func (r *Requester) handleReply(_ context.Context, msg *kafka.Message) error {
+	id := kafkalib.HeaderID(CorrelationIDHeader)(msg)
+
+	r.mu.Lock()
+	replies, ok := r.pending[id]
+	delete(r.pending, id)
+	r.mu.Unlock()
+
+	if !ok {
+		r.logger.Debug("reply without pending request dropped", slog.String(CorrelationIDHeader, id))
+		return nil
+	}
+	replies <- msg
+	return nil
+}
+
+// Close прекращает чтение ответов и закрывает клиентов
+func (r *Requester) Close() {
+	r.cancel()
+	<-r.done
+	r.consumer.Close()
+	r.producer.Close()
+}
+
+// correlationID возвращает случайный идентификатор запроса
+func correlationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate correlation id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// ReplyHandler обрабатывает запрос и возвращает ответ
+type ReplyHandler func(ctx context.Context, msg *kafka.Message) (kafkalib.Record, error)
+
+// Responder отправляет ответы на запросы по адресу из заголовков reply-to и reply-partition
+type Responder struct {
+	producer *kafkalib.Producer
+	logger   *slog.Logger
+}
+
+// NewResponder создает Responder. Если logger равен nil, используется slog.Default()
+func NewResponder(config map[string]string, logger *slog.Logger, opts ...kafkalib.Option) (*Responder, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	producer, err := kafkalib.NewMultiTopicProducer(config, logger, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Responder{producer: producer, logger: logger}, nil
+}
+
+// Handler возвращает обработчик консьюмера запросов. Ответ отправляется с correlation-id
+// запроса, а ошибка handler передается запрашивающему в заголовке reply-error и не
+// останавливает консьюмера. Обработчик завершается после доставки ответа и возвращает
+// ошибку, если ответ не доставлен: смещение запроса тогда не сохраняется, и запрос
+// обрабатывается повторно, пока не истечет его дедлайн (поэтому handler должен быть
+// идемпотентным). Запросы без reply-to
+// обрабатываются без ответа, а запросы с истекшим дедлайном пропускаются
+func (r *Responder) Handler(handler ReplyHandler) kafkalib.Handler {
+	return func(ctx context.Context, msg *kafka.Message) error {
+		if deadline, ok := requestDeadline(msg); ok && time.Now().After(deadline) {
+			r.logger.Warn("expired request skipped",
+				slog.String(kafkalib.LogKeyTopic, *msg.TopicPartition.Topic),
+				slog.String(CorrelationIDHeader, kafkalib.HeaderID(CorrelationIDHeader)(msg)))
+			return nil
+		}
+
+		record, err := handler(ctx, msg)
+
+		replyTo := kafkalib.HeaderID(ReplyToHeader)(msg)
+		if replyTo == "" {
+			return err
+		}
+		partition, perr := strconv.ParseInt(kafkalib.HeaderID(ReplyPartitionHeader)(msg), 10, 32)
+		if perr != nil {
+			return fmt.Errorf("invalid %s header: %w", ReplyPartitionHeader, perr)
+		}
+
+		headers := append([]kafka.Header(nil), record.Headers...)
+		headers = append(headers, kafka.Header{Key: CorrelationIDHeader, Value: []byte(kafkalib.HeaderID(CorrelationIDHeader)(msg))})
+		if err != nil {
+			r.logger.Warn("request handling failed",
+				slog.String(kafkalib.LogKeyTopic, *msg.TopicPartition.Topic),
+				slog.Any(kafkalib.LogKeyError, err))
+			record = kafkalib.Record{}
+			headers = append(headers, kafka.Header{Key: ErrorHeader, Value: []byte(err.Error())})
+		}
+
+		return r.reply(ctx, &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &replyTo, Partition: int32(partition)},
+			Key:            record.Key,
+			Value:          record.Value,
+			Headers:        headers,
+			Timestamp:      record.Timestamp,
+		})
+	}
+}
+
+// reply отправляет ответ и ждет подтверждения доставки
+func (r *Responder) reply(ctx context.Context, message *kafka.Message) error {
+	deliveries := make(chan kafka.Event, 1)
+	if err := r.producer.SendMessage(ctx, message, deliveries); err != nil {
+		return err
+	}
+
+	select {
+	case e := <-deliveries:
+		if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			return fmt.Errorf("failed to deliver reply: %w", m.TopicPartition.Error)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close закрывает продюсера
+func (r *Responder) Close() {
+	r.producer.Close()
+}
+
+// requestDeadline возвращает дедлайн запроса из заголовка request-deadline
+func requestDeadline(msg *kafka.Message) (time.Time, bool) {
+	value := kafkalib.HeaderID(DeadlineHeader)(msg)
+	if value == "" {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
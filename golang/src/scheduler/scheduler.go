@@ -0,0 +1,401 @@
+// Package scheduler пересылает отложенные сообщения, отправленные Producer.SendAt,
+// из топиков задержки в целевые топики. Партиция топика задержки приостанавливается,
+// пока не наступит срок ее первого сообщения. Пересланные сообщения и смещения фиксируются
+// в одной транзакции, поэтому перезапуск не приводит ни к потере, ни к повтору сообщений
+// для консьюмеров с isolation.level=read_committed
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/kafka-examples/golang/src/admin"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// Значения по умолчанию
+const (
+	defaultGroup      = "kafka-scheduler"
+	defaultPartitions = 3
+	defaultBatchSize  = 500
+	defaultBatchWait  = 100 * time.Millisecond
+	syncTimeout       = 30 * time.Second
+)
+
+// Config - параметры планировщика
+type Config struct {
+	// Buckets - топики задержки; должны совпадать с WithDelayBuckets продюсеров.
+	// По умолчанию kafka.DefaultDelayBuckets
+	Buckets kafkalib.DelayBuckets
+	// Group - группа консьюмеров планировщика
+	Group string
+	// Partitions и ReplicationFactor - параметры создаваемых топиков задержки;
+	// ReplicationFactor 0 - значение брокера
+	Partitions        int
+	ReplicationFactor int
+	// BatchSize и BatchWait ограничивают пакет сообщений партиции, после доставки
+	// которого фиксируются смещения
+	BatchSize int
+	BatchWait time.Duration
+}
+
+// partitionKey - партиция топика задержки
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// Scheduler пересылает отложенные сообщения в срок
+type Scheduler struct {
+	config   Config
+	logger   *slog.Logger
+	consumer *kafkalib.Consumer
+	producer *kafkalib.Producer
+
+	mu sync.Mutex
+	// timers возобновляют приостановленные партиции к сроку их первого сообщения
+	timers map[partitionKey]*time.Timer
+	closed bool
+}
+
+// New создает планировщик: создает недостающие топики задержки и подписывается на них.
+// Если logger равен nil, используется slog.Default()
+func New(config Config, clientConfig map[string]string, logger *slog.Logger, opts ...kafkalib.Option) (*Scheduler, error) {
+	if config.Buckets.Prefix == "" && len(config.Buckets.Delays) == 0 {
+		config.Buckets = kafkalib.DefaultDelayBuckets
+	}
+	if err := config.Buckets.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Group == "" {
+		config.Group = defaultGroup
+	}
+	if config.Partitions == 0 {
+		config.Partitions = defaultPartitions
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.BatchWait == 0 {
+		config.BatchWait = defaultBatchWait
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	s := &Scheduler{
+		config: config,
+		logger: logger,
+		timers: make(map[partitionKey]*time.Timer),
+	}
+	if err := s.open(clientConfig, opts); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// open создает топики задержки и клиентов
+func (s *Scheduler) open(clientConfig map[string]string, opts []kafkalib.Option) error {
+	if err := s.ensureTopics(clientConfig, opts); err != nil {
+		return err
+	}
+
+	// Транзакционный продюсер фиксирует пересланные сообщения вместе со смещениями
+	producerConfig := map[string]string{"transactional.id": transactionalID(s.config.Group)}
+	for k, v := range clientConfig {
+		producerConfig[k] = v
+	}
+
+	var err error
+	s.producer, err = kafkalib.NewMultiTopicProducer(producerConfig, s.logger, opts...)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	if err := s.producer.InitTransactions(ctx); err != nil {
+		return err
+	}
+
+	consumerConfig := map[string]string{"auto.offset.reset": "earliest"}
+	for k, v := range clientConfig {
+		consumerConfig[k] = v
+	}
+	consumerConfig["group.id"] = s.config.Group
+
+	// Пакеты собираются по партициям: сообщения партиции задержки упорядочены по сроку,
+	// поэтому обработка пакета останавливается на первом сообщении, срок которого не наступил
+	// Смещения фиксирует транзакция продюсера, а не консьюмер
+	s.consumer, err = kafkalib.NewConsumer(s.config.Buckets.Topics(), consumerConfig, s.logger,
+		append(append([]kafkalib.Option(nil), opts...), kafkalib.WithPartitionBatches(), kafkalib.WithoutOffsetCommit())...)
+	if err != nil {
+		return err
+	}
+	s.consumer.OnRevoked(s.stopTimers)
+	s.consumer.OnLost(s.stopTimers)
+	return nil
+}
+
+// ensureTopics создает недостающие топики задержки
+func (s *Scheduler) ensureTopics(clientConfig map[string]string, opts []kafkalib.Option) error {
+	client, err := admin.NewClient(clientConfig, s.logger, opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	specs := make([]admin.TopicSpec, 0, len(s.config.Buckets.Delays))
+	for _, topic := range s.config.Buckets.Topics() {
+		specs = append(specs, admin.TopicSpec{
+			Name:              topic,
+			Partitions:        s.config.Partitions,
+			ReplicationFactor: s.config.ReplicationFactor,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	_, err = client.EnsureTopics(ctx, specs)
+	return err
+}
+
+// Run пересылает сообщения до отмены ctx
+func (s *Scheduler) Run(ctx context.Context) error {
+	return s.consumer.RunBatch(ctx, s.forwardBatch, s.config.BatchSize, s.config.BatchWait)
+}
+
+// Close останавливает планировщик и закрывает клиентов
+func (s *Scheduler) Close() {
+	// Таймеры не должны возобновлять партиции закрытого консьюмера
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.stopTimers(nil)
+
+	if s.consumer != nil {
+		s.consumer.Close()
+	}
+	if s.producer != nil {
+		s.producer.Close()
+	}
+}
+
+// forwardBatch пересылает сообщения партиции, срок которых наступил. Если срок очередного
+// сообщения не наступил, партиция приостанавливается до этого срока, а смещения
+// фиксируются только для пересланных сообщений
+func (s *Scheduler) forwardBatch(ctx context.Context, msgs []*kafka.Message) error {
+	if err := s.producer.BeginTransaction(); err != nil {
+		return err
+	}
+
+	deliveries := make(chan kafka.Event, len(msgs))
+	sent := 0
+	processed := len(msgs)
+	var sendErr error
+
+	for i, msg := range msgs {
+		next, due, err := s.next(msg)
+		if err != nil {
+			// Без заголовков отложенной доставки сообщение некуда переслать
+			s.logger.Error("invalid delayed message skipped",
+				slog.String(kafkalib.LogKeyTopic, *msg.TopicPartition.Topic),
+				slog.Int(kafkalib.LogKeyPartition, int(msg.TopicPartition.Partition)),
+				slog.Any(kafkalib.LogKeyOffset, msg.TopicPartition.Offset),
+				slog.Any(kafkalib.LogKeyError, err))
+			continue
+		}
+		if time.Now().Before(due) {
+			processed = i
+			sendErr = s.pause(msg.TopicPartition, due)
+			break
+		}
+		if err := s.producer.SendMessage(ctx, next, deliveries); err != nil {
+			processed = i
+			sendErr = err
+			break
+		}
+		sent++
+	}
+
+	if err := s.commit(ctx, deliveries, sent, msgs[:processed]); err != nil {
+		// Пакет будет получен повторно, а пересланные сообщения отменены вместе с транзакцией
+		return err
+	}
+	if processed < len(msgs) {
+		return &kafkalib.PartialBatchError{Processed: processed, Err: sendErr}
+	}
+	return nil
+}
+
+// commit дожидается доставки пересланных сообщений и фиксирует транзакцию вместе с позицией
+// после обработанных сообщений; при ошибке транзакция отменяется. Пакет содержит сообщения
+// одной партиции (WithPartitionBatches)
+func (s *Scheduler) commit(ctx context.Context, deliveries chan kafka.Event, sent int, processed []*kafka.Message) error {
+	err := awaitDeliveries(ctx, deliveries, sent)
+	if err == nil && len(processed) > 0 {
+		position := processed[len(processed)-1].TopicPartition
+		position.Offset++
+		err = s.producer.SendOffsetsToTransaction(ctx, []kafka.TopicPartition{position}, s.consumer)
+	}
+	if err == nil {
+		err = s.producer.CommitTransaction(ctx)
+	}
+	if err != nil {
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), syncTimeout)
+		defer cancel()
+		return errors.Join(err, s.producer.AbortTransaction(abortCtx))
+	}
+	return nil
+}
+
+// transactionalID возвращает transactional.id экземпляра планировщика: идентификатор
+// уникален для процесса, а зомби-экземпляры после перебалансировки отсекаются
+// по метаданным группы в SendOffsetsToTransaction
+func transactionalID(group string) string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "scheduler"
+	}
+	return fmt.Sprintf("%s-%s-%d", group, hostname, os.Getpid())
+}
+
+// next возвращает сообщение для пересылки и срок, когда его нужно переслать: сообщение
+// ожидает в топике задержки не дольше его задержки и не позже времени доставки.
+// К сроку доставки сообщение пересылается в целевой топик, а до него - в топик
+// задержки, соответствующий оставшемуся времени
+func (s *Scheduler) next(msg *kafka.Message) (*kafka.Message, time.Time, error) {
+	delay, ok := s.config.Buckets.Delay(*msg.TopicPartition.Topic)
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("unknown delay topic %s", *msg.TopicPartition.Topic)
+	}
+	target := kafkalib.HeaderID(kafkalib.DelayTargetHeader)(msg)
+	if target == "" {
+		return nil, time.Time{}, fmt.Errorf("missing %s header", kafkalib.DelayTargetHeader)
+	}
+	until, err := strconv.ParseInt(kafkalib.HeaderID(kafkalib.DelayUntilHeader)(msg), 10, 64)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid %s header: %w", kafkalib.DelayUntilHeader, err)
+	}
+	deliverAt := time.UnixMilli(until)
+
+	due := msg.Timestamp.Add(delay)
+	if deliverAt.Before(due) {
+		due = deliverAt
+	}
+
+	next := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+	}
+	remaining := time.Until(deliverAt)
+	if remaining <= 0 {
+		// Партиция, выбранная Partitioner продюсера, сохраняет порядок сообщений ключа
+		// вместе с отправленными без задержки; без нее партицию выбирает librdkafka
+		if value := kafkalib.HeaderID(kafkalib.DelayPartitionHeader)(msg); value != "" {
+			partition, err := strconv.ParseInt(value, 10, 32)
+			if err != nil || partition < 0 {
+				return nil, time.Time{}, fmt.Errorf("invalid %s header %q", kafkalib.DelayPartitionHeader, value)
+			}
+			next.TopicPartition.Partition = int32(partition)
+		}
+		// Служебные заголовки, кроме идентификатора, в целевой топик не передаются
+		for _, h := range msg.Headers {
+			if h.Key != kafkalib.DelayTargetHeader && h.Key != kafkalib.DelayUntilHeader &&
+				h.Key != kafkalib.DelayPartitionHeader {
+				next.Headers = append(next.Headers, h)
+			}
+		}
+		next.TopicPartition.Topic = &target
+	} else {
+		topic := s.config.Buckets.Topic(s.config.Buckets.Bucket(remaining))
+		next.Headers = append([]kafka.Header(nil), msg.Headers...)
+		next.TopicPartition.Topic = &topic
+		next.Timestamp = time.Now()
+	}
+	return next, due, nil
+}
+
+// pause приостанавливает партицию до срока due
+func (s *Scheduler) pause(tp kafka.TopicPartition, due time.Time) error {
+	tp.Offset = kafka.OffsetInvalid
+	if err := s.consumer.Pause([]kafka.TopicPartition{tp}); err != nil {
+		return err
+	}
+
+	key := partitionKey{*tp.Topic, tp.Partition}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	if timer, ok := s.timers[key]; ok {
+		timer.Stop()
+	}
+	s.timers[key] = time.AfterFunc(time.Until(due), func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.closed {
+			return
+		}
+		delete(s.timers, key)
+		if err := s.consumer.Resume([]kafka.TopicPartition{tp}); err != nil {
+			s.logger.Error("failed to resume delay partition", slog.String(kafkalib.LogKeyTopic, key.topic),
+				slog.Int(kafkalib.LogKeyPartition, int(key.partition)), slog.Any(kafkalib.LogKeyError, err))
+		}
+	})
+	return nil
+}
+
+// stopTimers отменяет возобновление отозванных партиций; nil - всех партиций
+func (s *Scheduler) stopTimers(partitions []kafka.TopicPartition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if partitions == nil {
+		for key, timer := range s.timers {
+			timer.Stop()
+			delete(s.timers, key)
+		}
+		return
+	}
+	for _, tp := range partitions {
+		key := partitionKey{*tp.Topic, tp.Partition}
+		if timer, ok := s.timers[key]; ok {
+			timer.Stop()
+			delete(s.timers, key)
+		}
+	}
+}
+
+// awaitDeliveries ждет отчеты о доставке count сообщений
+func awaitDeliveries(ctx context.Context, deliveries chan kafka.Event, count int) error {
+	var errs []error
+	for i := 0; i < count; i++ {
+		var ev kafka.Event
+		select {
+		case ev = <-deliveries:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if delivered, ok := ev.(*kafka.Message); ok && delivered.TopicPartition.Error != nil {
+			errs = append(errs, delivered.TopicPartition.Error)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to deliver %d of %d messages: %w", len(errs), count, err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// storePartition - партиция хранилища
+type storePartition struct {
+	store     *store
+	partition int32
+}
+
+// batch - состояние обработки пакета: изменения хранилищ, которые сохраняются
+// только после доставки всех отправленных сообщений, и отчеты о доставке
+type batch struct {
+	ctx context.Context
+	app *App
+	// partition - партиция обрабатываемого сообщения и хранилищ
+	partition  int32
+	dirty      map[storePartition]map[string][]byte
+	deliveries *deliveries
+}
+
+// newBatch создает состояние обработки пакета
+func newBatch(ctx context.Context, app *App) *batch {
+	return &batch{
+		ctx:        ctx,
+		app:        app,
+		dirty:      make(map[storePartition]map[string][]byte),
+		deliveries: newDeliveries(),
+	}
+}
+
+// get возвращает значение ключа с учетом изменений пакета
+func (b *batch) get(st *store, key []byte) ([]byte, bool) {
+	if value, ok := b.dirty[storePartition{st, b.partition}][string(key)]; ok {
+		return value, value != nil
+	}
+	ps := st.partition(b.partition)
+	if ps == nil {
+		return nil, false
+	}
+	return ps.get(key)
+}
+
+// put запоминает новое значение ключа
+func (b *batch) put(st *store, key []byte, value []byte) {
+	sp := storePartition{st, b.partition}
+	if b.dirty[sp] == nil {
+		b.dirty[sp] = make(map[string][]byte)
+	}
+	b.dirty[sp][string(key)] = value
+}
+
+// send отправляет запись; opaque передается в отчет о доставке
+func (b *batch) send(topic string, partition int32, r Record, opaque interface{}) error {
+	b.deliveries.add()
+	err := b.app.producer.SendMessage(b.ctx, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: partition},
+		Key:            r.Key,
+		Value:          r.Value,
+		Headers:        append([]kafka.Header(nil), r.Headers...),
+		Timestamp:      r.Timestamp,
+		Opaque:         opaque,
+	}, b.deliveries.events)
+	if err != nil {
+		b.deliveries.cancel()
+	}
+	return err
+}
+
+// sendChangelogs отправляет последние значения измененных ключей в changelog-топики
+// в партиции, соответствующие партициям хранилищ
+func (b *batch) sendChangelogs() error {
+	for sp, entries := range b.dirty {
+		for key, value := range entries {
+			if err := b.send(sp.store.changelog, sp.partition, Record{Key: []byte(key), Value: value}, sp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// commit сохраняет изменения хранилищ с контрольными точками changelog-топиков
+func (b *batch) commit(offsets map[storePartition]int64) error {
+	for sp, entries := range b.dirty {
+		ps := sp.store.partition(sp.partition)
+		if ps == nil {
+			return fmt.Errorf("store %s partition %d is closed", sp.store.name, sp.partition)
+		}
+		if err := ps.apply(entries, offsets[sp]+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliveries собирает отчеты о доставке сообщений пакета
+type deliveries struct {
+	events  chan kafka.Event
+	pending sync.WaitGroup
+	errs    []error
+	// offsets - смещения последних доставленных записей changelog по партициям хранилищ
+	offsets map[storePartition]int64
+}
+
+// newDeliveries запускает сбор отчетов о доставке
+func newDeliveries() *deliveries {
+	d := &deliveries{
+		events:  make(chan kafka.Event, 1000),
+		offsets: make(map[storePartition]int64),
+	}
+	go d.collect()
+	return d
+}
+
+// collect обрабатывает отчеты до закрытия канала
+func (d *deliveries) collect() {
+	for ev := range d.events {
+		if m, ok := ev.(*kafka.Message); ok {
+			if m.TopicPartition.Error != nil {
+				d.errs = append(d.errs, m.TopicPartition.Error)
+			} else if sp, ok := m.Opaque.(storePartition); ok {
+				offset := int64(m.TopicPartition.Offset)
+				if current, ok := d.offsets[sp]; !ok || offset > current {
+					d.offsets[sp] = offset
+				}
+			}
+		}
+		d.pending.Done()
+	}
+}
+
+// add учитывает отправляемое сообщение
+func (d *deliveries) add() {
+	d.pending.Add(1)
+}
+
+// cancel снимает учет сообщения, которое не удалось отправить
+func (d *deliveries) cancel() {
+	d.pending.Done()
+}
+
+// wait дожидается отчетов о доставке всех отправленных сообщений
+func (d *deliveries) wait() (map[storePartition]int64, error) {
+	d.pending.Wait()
+	close(d.events)
+
+	if err := errors.Join(d.errs...); err != nil {
+		return nil, fmt.Errorf("failed to deliver %d messages: %w", len(d.errs), err)
+	}
+	return d.offsets, nil
+}
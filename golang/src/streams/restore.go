@@ -0,0 +1,196 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// Восстановление выполняется в цикле получения сообщений, поэтому одна попытка ограничена
+// restoreTimeout и долей max.poll.interval.ms; прочитанное за попытку сохраняется,
+// следующая попытка продолжает с сохраненной позиции
+const (
+	restoreTimeout = 30 * time.Second
+	// defaultMaxPollInterval - значение max.poll.interval.ms по умолчанию
+	defaultMaxPollInterval = 5 * time.Minute
+)
+
+// changelogPartition - партиция changelog-топика
+type changelogPartition struct {
+	topic     string
+	partition int32
+}
+
+// restoreAssigned восстанавливает хранилища назначенных партиций до начала их обработки.
+// При ошибке или нехватке времени восстановление продолжается при получении пакетов партиции
+func (a *App) restoreAssigned(partitions []kafka.TopicPartition) {
+	var targets []storePartition
+	for _, tp := range partitions {
+		targets = append(targets, a.closedStores(*tp.Topic, tp.Partition)...)
+	}
+	if err := a.restore(targets); err != nil {
+		a.logger.Error("failed to restore state stores", slog.Any(kafkalib.LogKeyError, err))
+	}
+}
+
+// ensureRestored восстанавливает хранилища партиции, если это не удалось при назначении.
+// Пока восстановление не завершено, пакет не обрабатывается и будет получен повторно
+func (a *App) ensureRestored(topic string, partition int32) error {
+	return a.restore(a.closedStores(topic, partition))
+}
+
+// closedStores возвращает неоткрытые хранилища партиции входного топика
+func (a *App) closedStores(topic string, partition int32) []storePartition {
+	var targets []storePartition
+	for _, st := range a.stores {
+		if st.source == topic && st.partition(partition) == nil {
+			targets = append(targets, storePartition{st, partition})
+		}
+	}
+	return targets
+}
+
+// closePartitions закрывает хранилища отозванных или потерянных партиций
+func (a *App) closePartitions(partitions []kafka.TopicPartition) {
+	for _, tp := range partitions {
+		for _, st := range a.stores {
+			if st.source == *tp.Topic {
+				st.close(tp.Partition)
+			}
+		}
+	}
+}
+
+// restoreTimeout возвращает время одной попытки восстановления: не больше restoreTimeout
+// и трети max.poll.interval.ms, чтобы консьюмер не был исключен из группы
+func (a *App) restoreTimeout() time.Duration {
+	interval := defaultMaxPollInterval
+	if ms, err := strconv.Atoi(a.clientConfig["max.poll.interval.ms"]); err == nil && ms > 0 {
+		interval = time.Duration(ms) * time.Millisecond
+	}
+	return min(restoreTimeout, interval/3)
+}
+
+// restore открывает партиции хранилищ и дочитывает в них changelog-топики
+// от контрольных точек до текущего конца. Не восстановленные до конца партиции
+// закрываются и будут восстановлены повторно
+func (a *App) restore(targets []storePartition) error {
+	if len(targets) == 0 {
+		return nil
+	}
+	if err := a.load(targets); err != nil {
+		for _, sp := range targets {
+			sp.store.close(sp.partition)
+		}
+		return err
+	}
+	return nil
+}
+
+// load открывает партиции хранилищ и применяет к ним записи changelog-топиков
+func (a *App) load(targets []storePartition) error {
+	stores := make(map[changelogPartition]*partitionStore, len(targets))
+	assignment := make([]kafka.TopicPartition, 0, len(targets))
+	for _, sp := range targets {
+		ps, err := sp.store.open(sp.partition)
+		if err != nil {
+			return err
+		}
+
+		offset := kafka.OffsetBeginning
+		if ps.checkpoint >= 0 {
+			offset = kafka.Offset(ps.checkpoint)
+		}
+		stores[changelogPartition{sp.store.changelog, sp.partition}] = ps
+		assignment = append(assignment, kafka.TopicPartition{Topic: &sp.store.changelog, Partition: sp.partition, Offset: offset})
+	}
+
+	consumerConfig := make(map[string]string, len(a.clientConfig)+2)
+	for k, v := range a.clientConfig {
+		consumerConfig[k] = v
+	}
+	consumerConfig["group.id"] = a.config.ApplicationID + "-restore"
+	consumerConfig["auto.offset.reset"] = "earliest"
+
+	// Позиция восстановления берется из контрольных точек хранилищ, смещения группы не фиксируются
+	consumer, err := kafkalib.NewConsumer(nil, consumerConfig, a.logger,
+		append(append([]kafkalib.Option(nil), a.opts...), kafkalib.WithAssignment(assignment), kafkalib.WithoutOffsetCommit())...)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	// Читаем до верхней границы каждой партиции на момент восстановления
+	ends := make(map[changelogPartition]int64, len(stores))
+	remaining := make(map[changelogPartition]bool)
+	for cp, ps := range stores {
+		low, high, err := consumer.WatermarkOffsets(cp.topic, cp.partition)
+		if err != nil {
+			return err
+		}
+		ends[cp] = high
+		if high > max(low, ps.checkpoint) {
+			remaining[cp] = true
+		}
+	}
+
+	entries := make(map[changelogPartition]map[string][]byte, len(stores))
+	positions := make(map[changelogPartition]int64, len(stores))
+	restored := 0
+	if len(remaining) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), a.restoreTimeout())
+		defer cancel()
+
+		err = consumer.Run(ctx, func(_ context.Context, msg *kafka.Message) error {
+			cp := changelogPartition{*msg.TopicPartition.Topic, msg.TopicPartition.Partition}
+			if entries[cp] == nil {
+				entries[cp] = make(map[string][]byte)
+			}
+			entries[cp][string(msg.Key)] = msg.Value
+			positions[cp] = int64(msg.TopicPartition.Offset) + 1
+			restored++
+
+			if int64(msg.TopicPartition.Offset)+1 >= ends[cp] {
+				delete(remaining, cp)
+			}
+			if len(remaining) == 0 {
+				return kafkalib.ErrStopConsuming
+			}
+			return nil
+		})
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Сохраняем прочитанное, чтобы следующая попытка продолжила с этой позиции
+			for cp, ps := range stores {
+				position, ok := ends[cp], true
+				if remaining[cp] {
+					position, ok = positions[cp]
+				}
+				if !ok {
+					continue
+				}
+				if err := ps.apply(entries[cp], position); err != nil {
+					return err
+				}
+			}
+			a.logger.Info("state stores partially restored", slog.Int("partitions", len(remaining)), slog.Int("records", restored))
+			return fmt.Errorf("failed to restore state stores: timeout")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore state stores: %w", err)
+		}
+	}
+
+	for cp, ps := range stores {
+		if err := ps.apply(entries[cp], ends[cp]); err != nil {
+			return err
+		}
+	}
+	a.logger.Info("state stores restored", slog.Int("partitions", len(stores)), slog.Int("records", restored))
+	return nil
+}
@@ -0,0 +1,332 @@
+package streams
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// compactMinEntries - минимальное число записей журнала хранилища, после которого
+// журнал переписывается снимком, если записей вдвое больше, чем ключей
+const compactMinEntries = 1000
+
+// store - хранилище агрегата, разделенное по партициям входного топика
+type store struct {
+	name string
+	// source - входной топик, партициям которого соответствуют партиции хранилища
+	source    string
+	changelog string
+	dir       string
+	logger    *slog.Logger
+
+	mu         sync.RWMutex
+	partitions map[int32]*partitionStore
+}
+
+// newStore регистрирует хранилище name
+func (a *App) newStore(name string) *store {
+	st := &store{
+		name:       name,
+		changelog:  a.internalTopic(name, "changelog"),
+		dir:        filepath.Join(a.config.StateDir, a.config.ApplicationID),
+		logger:     a.logger,
+		partitions: make(map[int32]*partitionStore),
+	}
+
+	if !validName.MatchString(name) {
+		a.errs = append(a.errs, fmt.Errorf("invalid store name %q", name))
+	}
+	for _, existing := range a.stores {
+		if existing.name == name {
+			a.errs = append(a.errs, fmt.Errorf("duplicate store %s", name))
+		}
+	}
+	a.stores = append(a.stores, st)
+	return st
+}
+
+// partition возвращает открытую партицию хранилища или nil
+func (s *store) partition(partition int32) *partitionStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.partitions[partition]
+}
+
+// open открывает партицию хранилища с диска
+func (s *store) open(partition int32) (*partitionStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ps, ok := s.partitions[partition]; ok {
+		return ps, nil
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	ps, err := openPartitionStore(filepath.Join(s.dir, fmt.Sprintf("%s-%d", s.name, partition)))
+	if err != nil {
+		return nil, err
+	}
+	s.partitions[partition] = ps
+	return ps, nil
+}
+
+// close закрывает партицию хранилища; ее состояние остается на диске
+func (s *store) close(partition int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ps, ok := s.partitions[partition]; ok {
+		if err := ps.close(); err != nil {
+			s.logger.Error("failed to close store", slog.String("store", s.name),
+				slog.Int(kafkalib.LogKeyPartition, int(partition)), slog.Any(kafkalib.LogKeyError, err))
+		}
+		delete(s.partitions, partition)
+	}
+}
+
+// closeAll закрывает все партиции хранилища
+func (s *store) closeAll() {
+	s.mu.RLock()
+	partitions := make([]int32, 0, len(s.partitions))
+	for partition := range s.partitions {
+		partitions = append(partitions, partition)
+	}
+	s.mu.RUnlock()
+
+	for _, partition := range partitions {
+		s.close(partition)
+	}
+}
+
+// get ищет ключ во всех открытых партициях
+func (s *store) get(key []byte) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, ps := range s.partitions {
+		if value, ok := ps.get(key); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// partitionStore - партиция хранилища: значения в памяти и журнал изменений на диске.
+// Рядом с журналом хранится контрольная точка - смещение changelog-топика, с которого
+// нужно продолжить восстановление
+type partitionStore struct {
+	path string
+
+	mu      sync.RWMutex
+	data    map[string][]byte
+	file    *os.File
+	entries int
+	// checkpoint - следующее смещение changelog-топика; -1, если неизвестно
+	checkpoint int64
+}
+
+// openPartitionStore загружает журнал path.log и контрольную точку path.checkpoint.
+// Без контрольной точки содержимое журнала не учитывается и восстанавливается из changelog
+func openPartitionStore(path string) (*partitionStore, error) {
+	ps := &partitionStore{path: path, data: make(map[string][]byte), checkpoint: -1}
+
+	checkpoint, err := os.ReadFile(path + ".checkpoint")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read store checkpoint: %w", err)
+	default:
+		ps.checkpoint, err = strconv.ParseInt(strings.TrimSpace(string(checkpoint)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid store checkpoint %s: %w", path, err)
+		}
+	}
+
+	size := 0
+	if ps.checkpoint >= 0 {
+		data, err := os.ReadFile(path + ".log")
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read store: %w", err)
+		}
+		size = ps.load(data)
+	}
+
+	// Недописанная при сбое последняя запись журнала отбрасывается
+	ps.file, err = os.OpenFile(path+".log", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	if err := ps.file.Truncate(int64(size)); err != nil {
+		ps.file.Close()
+		return nil, fmt.Errorf("failed to truncate store: %w", err)
+	}
+	if _, err := ps.file.Seek(int64(size), 0); err != nil {
+		ps.file.Close()
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	return ps, nil
+}
+
+// load применяет записи журнала и возвращает размер целых записей
+func (ps *partitionStore) load(data []byte) int {
+	pos := 0
+	for pos < len(data) {
+		key, value, n := decodeEntry(data[pos:])
+		if n == 0 {
+			break
+		}
+		ps.set(string(key), value)
+		ps.entries++
+		pos += n
+	}
+	return pos
+}
+
+// get возвращает значение ключа
+func (ps *partitionStore) get(key []byte) ([]byte, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	value, ok := ps.data[string(key)]
+	return value, ok
+}
+
+// set изменяет значение ключа в памяти; nil удаляет ключ
+func (ps *partitionStore) set(key string, value []byte) {
+	if value == nil {
+		delete(ps.data, key)
+	} else {
+		ps.data[key] = value
+	}
+}
+
+// apply дописывает изменения в журнал, применяет их и сохраняет контрольную точку
+func (ps *partitionStore) apply(entries map[string][]byte, checkpoint int64) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var buf []byte
+	for key, value := range entries {
+		buf = appendEntry(buf, []byte(key), value)
+	}
+	if _, err := ps.file.Write(buf); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := ps.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync store: %w", err)
+	}
+	for key, value := range entries {
+		ps.set(key, value)
+	}
+	ps.entries += len(entries)
+
+	if ps.entries >= compactMinEntries && ps.entries > 2*len(ps.data) {
+		if err := ps.compact(); err != nil {
+			return err
+		}
+	}
+	return ps.writeCheckpoint(checkpoint)
+}
+
+// compact заменяет журнал снимком текущих значений
+func (ps *partitionStore) compact() error {
+	var buf []byte
+	for key, value := range ps.data {
+		buf = appendEntry(buf, []byte(key), value)
+	}
+	if err := writeFileAtomic(ps.path+".log", buf); err != nil {
+		return fmt.Errorf("failed to compact store: %w", err)
+	}
+
+	file, err := os.OpenFile(ps.path+".log", os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen store: %w", err)
+	}
+	ps.file.Close()
+	ps.file = file
+	ps.entries = len(ps.data)
+	return nil
+}
+
+// writeCheckpoint сохраняет контрольную точку
+func (ps *partitionStore) writeCheckpoint(checkpoint int64) error {
+	if err := writeFileAtomic(ps.path+".checkpoint", strconv.AppendInt(nil, checkpoint, 10)); err != nil {
+		return fmt.Errorf("failed to write store checkpoint: %w", err)
+	}
+	ps.checkpoint = checkpoint
+	return nil
+}
+
+// close закрывает журнал
+func (ps *partitionStore) close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.file.Close()
+}
+
+// appendEntry кодирует запись журнала: длина ключа, ключ, длина значения
+// (-1 для удаления) и значение
+func appendEntry(buf []byte, key []byte, value []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	if value == nil {
+		return binary.AppendVarint(buf, -1)
+	}
+	buf = binary.AppendVarint(buf, int64(len(value)))
+	return append(buf, value...)
+}
+
+// decodeEntry декодирует запись журнала; n равно 0, если запись неполная
+func decodeEntry(data []byte) (key []byte, value []byte, n int) {
+	keyLen, k := binary.Uvarint(data)
+	if k <= 0 || uint64(len(data)-k) < keyLen {
+		return nil, nil, 0
+	}
+	pos := k + int(keyLen)
+	key = data[k:pos]
+
+	valueLen, v := binary.Varint(data[pos:])
+	if v <= 0 || valueLen < -1 {
+		return nil, nil, 0
+	}
+	pos += v
+	if valueLen == -1 {
+		return key, nil, pos
+	}
+	if int64(len(data)-pos) < valueLen {
+		return nil, nil, 0
+	}
+	// Значение копируется, чтобы не удерживать в памяти весь прочитанный журнал
+	value = append([]byte{}, data[pos:pos+int(valueLen)]...)
+	return key, value, pos + int(valueLen)
+}
+
+// writeFileAtomic записывает файл через временный файл и переименование
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,375 @@
+// Package streams - легковесная потоковая обработка поверх Consumer и Producer:
+// цепочки Filter/Map/FlatMap, агрегаты по ключу GroupByKey/Aggregate и запись в топики To.
+// Состояние агрегатов хранится на диске по партициям и дублируется в компактируемые
+// changelog-топики, из которых восстанавливается при назначении партиции
+package streams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/kafka-examples/golang/src/admin"
+	kafkalib "github.com/kafka-examples/golang/src/kafka"
+)
+
+// Значения по умолчанию
+const (
+	defaultBatchSize = 100
+	defaultBatchWait = 100 * time.Millisecond
+	syncTimeout      = 30 * time.Second
+)
+
+// validName - допустимые имена приложения и хранилищ: они входят в имена топиков и файлов
+var validName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// Config - параметры приложения потоковой обработки
+type Config struct {
+	// ApplicationID - группа консьюмеров и префикс служебных топиков
+	ApplicationID string
+	// StateDir - каталог хранилищ состояния; у каждого экземпляра приложения должен быть свой.
+	// По умолчанию <временный каталог>/kafka-streams
+	StateDir string
+	// ReplicationFactor - фактор репликации служебных топиков; 0 - значение брокера
+	ReplicationFactor int
+	// BatchSize и BatchWait ограничивают пакет сообщений партиции, после обработки
+	// которого фиксируются смещения и сохраняется состояние
+	BatchSize int
+	BatchWait time.Duration
+}
+
+// Record - сообщение потока
+type Record struct {
+	Key       []byte
+	Value     []byte
+	Headers   []kafka.Header
+	Timestamp time.Time
+}
+
+// processor обрабатывает запись в рамках пакета
+type processor func(b *batch, r Record) error
+
+// App - приложение потоковой обработки: топология потоков, построенная вызовами
+// Stream, и клиенты, которые ее выполняют
+type App struct {
+	config       Config
+	clientConfig map[string]string
+	opts         []kafkalib.Option
+	logger       *slog.Logger
+
+	// sources - корневые потоки по входным топикам, включая топики перепартиционирования
+	sources map[string]*Stream
+	// repartitions - топики перепартиционирования и топики, из которых в них пишутся записи
+	repartitions map[string]string
+	stores       []*store
+	// errs - ошибки построения топологии; возвращаются из Run
+	errs []error
+
+	consumer *kafkalib.Consumer
+	producer *kafkalib.Producer
+}
+
+// New создает приложение. Топология строится вызовами Stream до запуска Run.
+// Если logger равен nil, используется slog.Default()
+func New(config Config, clientConfig map[string]string, logger *slog.Logger, opts ...kafkalib.Option) (*App, error) {
+	if !validName.MatchString(config.ApplicationID) {
+		return nil, fmt.Errorf("invalid application id %q", config.ApplicationID)
+	}
+	if config.StateDir == "" {
+		config.StateDir = filepath.Join(os.TempDir(), "kafka-streams")
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.BatchWait == 0 {
+		config.BatchWait = defaultBatchWait
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &App{
+		config:       config,
+		clientConfig: clientConfig,
+		opts:         opts,
+		logger:       logger.With(slog.String("application", config.ApplicationID)),
+		sources:      make(map[string]*Stream),
+		repartitions: make(map[string]string),
+	}, nil
+}
+
+// Stream возвращает поток сообщений топика
+func (a *App) Stream(topic string) *Stream {
+	if s, ok := a.sources[topic]; ok {
+		return s
+	}
+	s := &Stream{app: a, source: topic}
+	a.sources[topic] = s
+	return s
+}
+
+// internalTopic возвращает имя служебного топика хранилища
+func (a *App) internalTopic(name string, suffix string) string {
+	return fmt.Sprintf("%s-%s-%s", a.config.ApplicationID, name, suffix)
+}
+
+// Run создает служебные топики, подписывается на входные топики и обрабатывает сообщения
+// до отмены ctx. Смещения фиксируются после доставки результатов пакета и сохранения
+// состояния (семантика at-least-once: после сбоя сообщение может быть учтено в агрегате повторно)
+func (a *App) Run(ctx context.Context) error {
+	if len(a.sources) == 0 {
+		return errors.New("topology has no streams")
+	}
+	if err := errors.Join(a.errs...); err != nil {
+		return fmt.Errorf("invalid topology: %w", err)
+	}
+	if err := a.ensureTopics(ctx); err != nil {
+		return err
+	}
+
+	// Идемпотентный продюсер сохраняет порядок сообщений в партиции при повторных отправках
+	producerConfig := map[string]string{"enable.idempotence": "true"}
+	for k, v := range a.clientConfig {
+		producerConfig[k] = v
+	}
+	var err error
+	a.producer, err = kafkalib.NewMultiTopicProducer(producerConfig, a.logger, a.opts...)
+	if err != nil {
+		return err
+	}
+
+	consumerConfig := map[string]string{"auto.offset.reset": "earliest"}
+	for k, v := range a.clientConfig {
+		consumerConfig[k] = v
+	}
+	consumerConfig["group.id"] = a.config.ApplicationID
+
+	topics := make([]string, 0, len(a.sources))
+	for topic := range a.sources {
+		topics = append(topics, topic)
+	}
+
+	// Пакеты собираются по партициям: пакет изменяет хранилища одной партиции
+	a.consumer, err = kafkalib.NewConsumer(topics, consumerConfig, a.logger,
+		append(append([]kafkalib.Option(nil), a.opts...), kafkalib.WithPartitionBatches())...)
+	if err != nil {
+		return err
+	}
+	a.consumer.OnAssigned(a.restoreAssigned)
+	a.consumer.OnRevoked(a.closePartitions)
+	a.consumer.OnLost(a.closePartitions)
+
+	return a.consumer.RunBatch(ctx, a.processBatch, a.config.BatchSize, a.config.BatchWait)
+}
+
+// ensureTopics создает changelog-топики хранилищ и топики перепартиционирования
+// с числом партиций топиков, из которых они заполняются
+func (a *App) ensureTopics(ctx context.Context) error {
+	client, err := admin.NewClient(a.clientConfig, a.logger, a.opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	partitions := make(map[string]int)
+	var count func(topic string) (int, error)
+	count = func(topic string) (int, error) {
+		if n, ok := partitions[topic]; ok {
+			return n, nil
+		}
+		var n int
+		var err error
+		if origin, ok := a.repartitions[topic]; ok {
+			n, err = count(origin)
+		} else {
+			var descriptions []admin.TopicDescription
+			descriptions, err = client.DescribeTopics(ctx, []string{topic})
+			if err == nil {
+				n = descriptions[0].Partitions
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		partitions[topic] = n
+		return n, nil
+	}
+
+	var specs []admin.TopicSpec
+	for topic := range a.repartitions {
+		n, err := count(topic)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, admin.TopicSpec{Name: topic, Partitions: n, ReplicationFactor: a.config.ReplicationFactor})
+	}
+	for _, st := range a.stores {
+		n, err := count(st.source)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, admin.TopicSpec{
+			Name:              st.changelog,
+			Partitions:        n,
+			ReplicationFactor: a.config.ReplicationFactor,
+			CleanupPolicy:     "compact",
+		})
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+
+	_, err = client.EnsureTopics(ctx, specs)
+	return err
+}
+
+// Close останавливает обработку, закрывает клиентов и хранилища
+func (a *App) Close() {
+	if a.consumer != nil {
+		a.consumer.Close()
+	}
+	if a.producer != nil {
+		a.producer.Close()
+	}
+	for _, st := range a.stores {
+		st.closeAll()
+	}
+}
+
+// processBatch пропускает сообщения партиции через топологию, отправляет результаты
+// и изменения хранилищ в changelog-топики и после их доставки сохраняет состояние на диск
+func (a *App) processBatch(ctx context.Context, msgs []*kafka.Message) error {
+	b := newBatch(ctx, a)
+
+	var err error
+	for _, msg := range msgs {
+		root, ok := a.sources[*msg.TopicPartition.Topic]
+		if !ok {
+			continue
+		}
+		b.partition = msg.TopicPartition.Partition
+		if err = a.ensureRestored(root.source, b.partition); err != nil {
+			break
+		}
+		err = root.forward(b, Record{Key: msg.Key, Value: msg.Value, Headers: msg.Headers, Timestamp: msg.Timestamp})
+		if err != nil {
+			err = fmt.Errorf("failed to process %s [%d] at offset %v: %w",
+				*msg.TopicPartition.Topic, msg.TopicPartition.Partition, msg.TopicPartition.Offset, err)
+			break
+		}
+	}
+	if err == nil {
+		err = b.sendChangelogs()
+	}
+
+	// Дожидаемся всех отправленных сообщений, даже если пакет не обработан
+	offsets, deliveryErr := b.deliveries.wait()
+	if err = errors.Join(err, deliveryErr); err != nil {
+		return err
+	}
+	return b.commit(offsets)
+}
+
+// Stream - поток записей. Операции возвращают новый поток; к одному потоку можно
+// применить несколько операций, тогда каждая получит все его записи
+type Stream struct {
+	app *App
+	// source - входной топик, партиция сообщения которого определяет партицию хранилищ
+	source string
+	// keyChanged - ключ мог измениться после чтения из source: перед агрегацией
+	// записи перепартиционируются по новому ключу
+	keyChanged bool
+	next       []processor
+}
+
+// forward передает запись следующим операциям
+func (s *Stream) forward(b *batch, r Record) error {
+	for _, p := range s.next {
+		if err := p(b, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// child добавляет операцию, результат которой передается в новый поток
+func (s *Stream) child(keyChanged bool, process func(b *batch, r Record, out *Stream) error) *Stream {
+	out := &Stream{app: s.app, source: s.source, keyChanged: s.keyChanged || keyChanged}
+	s.next = append(s.next, func(b *batch, r Record) error {
+		return process(b, r, out)
+	})
+	return out
+}
+
+// Filter оставляет записи, для которых predicate возвращает true
+func (s *Stream) Filter(predicate func(Record) bool) *Stream {
+	return s.child(false, func(b *batch, r Record, out *Stream) error {
+		if !predicate(r) {
+			return nil
+		}
+		return out.forward(b, r)
+	})
+}
+
+// Map преобразует каждую запись; ключ может измениться
+func (s *Stream) Map(mapper func(Record) (Record, error)) *Stream {
+	return s.child(true, func(b *batch, r Record, out *Stream) error {
+		mapped, err := mapper(r)
+		if err != nil {
+			return err
+		}
+		return out.forward(b, mapped)
+	})
+}
+
+// MapValues преобразует значения записей; в отличие от Map ключ сохраняется,
+// поэтому последующая агрегация не требует перепартиционирования
+func (s *Stream) MapValues(mapper func(value []byte) ([]byte, error)) *Stream {
+	return s.child(false, func(b *batch, r Record, out *Stream) error {
+		value, err := mapper(r.Value)
+		if err != nil {
+			return err
+		}
+		r.Value = value
+		return out.forward(b, r)
+	})
+}
+
+// FlatMap преобразует запись в ноль или несколько записей; ключи могут измениться
+func (s *Stream) FlatMap(mapper func(Record) ([]Record, error)) *Stream {
+	return s.child(true, func(b *batch, r Record, out *Stream) error {
+		records, err := mapper(r)
+		if err != nil {
+			return err
+		}
+		for _, mapped := range records {
+			if err := out.forward(b, mapped); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// To отправляет записи в topic
+func (s *Stream) To(topic string) {
+	s.next = append(s.next, func(b *batch, r Record) error {
+		return b.send(topic, kafka.PartitionAny, r, nil)
+	})
+}
+
+// GroupByKey группирует записи по ключу для агрегации. Если ключ мог измениться
+// (Map, FlatMap), записи перед агрегацией проходят через топик перепартиционирования,
+// чтобы записи с одним ключом обрабатывались в одной партиции
+func (s *Stream) GroupByKey() *GroupedStream {
+	return &GroupedStream{stream: s}
+}
@@ -0,0 +1,82 @@
+package streams
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Aggregator вычисляет новое значение агрегата ключа key по записи со значением value
+type Aggregator func(key []byte, value []byte, aggregate []byte) ([]byte, error)
+
+// GroupedStream - поток, сгруппированный по ключу
+type GroupedStream struct {
+	stream *Stream
+}
+
+// Aggregate агрегирует записи по ключу в хранилище name. Для первой записи ключа
+// агрегат равен initializer(). Записи без ключа пропускаются. Возвращает таблицу
+// текущих значений агрегатов
+func (g *GroupedStream) Aggregate(name string, initializer func() []byte, aggregator Aggregator) *Table {
+	s := g.stream
+	a := s.app
+
+	st := a.newStore(name)
+
+	// Записи с измененным ключом отправляются в топик перепартиционирования, который
+	// читается как отдельный входной топик
+	if s.keyChanged {
+		topic := a.internalTopic(name, "repartition")
+		a.repartitions[topic] = s.source
+		s.To(topic)
+		s = a.Stream(topic)
+	}
+	st.source = s.source
+
+	updates := &Stream{app: a, source: s.source}
+	s.next = append(s.next, func(b *batch, r Record) error {
+		if r.Key == nil {
+			return nil
+		}
+		current, ok := b.get(st, r.Key)
+		if !ok {
+			current = initializer()
+		}
+		updated, err := aggregator(r.Key, r.Value, current)
+		if err != nil {
+			return err
+		}
+		b.put(st, r.Key, updated)
+		return updates.forward(b, Record{Key: r.Key, Value: updated, Headers: r.Headers, Timestamp: r.Timestamp})
+	})
+
+	return &Table{store: st, updates: updates}
+}
+
+// Count считает записи каждого ключа; значения агрегата - десятичные числа
+func (g *GroupedStream) Count(name string) *Table {
+	return g.Aggregate(name, func() []byte { return []byte("0") }, func(_ []byte, _ []byte, aggregate []byte) ([]byte, error) {
+		n, err := strconv.ParseInt(string(aggregate), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid count %q: %w", aggregate, err)
+		}
+		return strconv.AppendInt(nil, n+1, 10), nil
+	})
+}
+
+// Table - текущие значения агрегатов по ключам
+type Table struct {
+	store   *store
+	updates *Stream
+}
+
+// ToStream возвращает поток изменений таблицы: запись с новым значением агрегата
+// на каждую агрегированную запись
+func (t *Table) ToStream() *Stream {
+	return t.updates
+}
+
+// Get возвращает значение агрегата ключа из локального хранилища. Доступны только
+// ключи партиций, назначенных этому экземпляру приложения
+func (t *Table) Get(key []byte) ([]byte, bool) {
+	return t.store.get(key)
+}
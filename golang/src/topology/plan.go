@@ -0,0 +1,250 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/kafka-examples/golang/src/admin"
+	"github.com/riferrei/srclient"
+)
+
+// Коды ошибок Schema Registry
+const (
+	registrySubjectNotFound = 40401
+	registrySchemaNotFound  = 40403
+)
+
+// SchemaChange - изменение subject в Schema Registry
+type SchemaChange struct {
+	Subject string
+	// Register - зарегистрировать новую версию схемы
+	Register bool
+	Schema   string
+	Type     srclient.SchemaType
+	// Compatibility - новый уровень совместимости; пусто, если не меняется
+	Compatibility string
+	// CurrentCompatibility - текущий уровень совместимости subject
+	CurrentCompatibility string
+}
+
+// Plan - изменения, необходимые для приведения кластера к топологии
+type Plan struct {
+	Topics     []admin.Change
+	CreateACLs kafka.ACLBindings
+	DeleteACLs kafka.ACLBindings
+	Schemas    []SchemaChange
+}
+
+// Empty сообщает, что кластер уже соответствует топологии
+func (p *Plan) Empty() bool {
+	return len(p.Topics) == 0 && len(p.CreateACLs) == 0 && len(p.DeleteACLs) == 0 && len(p.Schemas) == 0
+}
+
+// Write выводит план в виде списка изменений
+func (p *Plan) Write(w io.Writer) {
+	if p.Empty() {
+		fmt.Fprintln(w, "No changes. Cluster matches the topology.")
+		return
+	}
+
+	for _, change := range p.Topics {
+		fmt.Fprintln(w, change)
+	}
+	for _, acl := range p.CreateACLs {
+		fmt.Fprintf(w, "+ acl %s\n", admin.FormatACL(acl))
+	}
+	for _, acl := range p.DeleteACLs {
+		fmt.Fprintf(w, "- acl %s\n", admin.FormatACL(acl))
+	}
+	for _, s := range p.Schemas {
+		if s.Register {
+			fmt.Fprintf(w, "+ schema %s (%s)\n", s.Subject, s.Type)
+		}
+		if s.Compatibility != "" {
+			fmt.Fprintf(w, "~ compatibility of %s: %s -> %s\n",
+				s.Subject, defaultString(s.CurrentCompatibility, "(global)"), s.Compatibility)
+		}
+	}
+
+	fmt.Fprintf(w, "\nPlan: %d topic, %d acl to add, %d acl to delete, %d schema changes.\n",
+		len(p.Topics), len(p.CreateACLs), len(p.DeleteACLs), len(p.Schemas))
+}
+
+// Planner сравнивает топологию с кластером и применяет изменения
+type Planner struct {
+	admin *admin.Client
+	// registry может быть nil, если топология не содержит схем
+	registry srclient.ISchemaRegistryClient
+}
+
+// NewPlanner создает планировщик; registry нужен только для топологий со схемами
+func NewPlanner(client *admin.Client, registry srclient.ISchemaRegistryClient) *Planner {
+	return &Planner{admin: client, registry: registry}
+}
+
+// Plan вычисляет изменения. Топики, отсутствующие в топологии, не удаляются;
+// ACL удаляются только для участников, упомянутых в топологии
+func (p *Planner) Plan(ctx context.Context, t *Topology) (*Plan, error) {
+	plan := &Plan{}
+
+	specs := make([]admin.TopicSpec, 0, len(t.Topics))
+	for _, topic := range t.Topics {
+		spec, err := topic.spec()
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) > 0 {
+		changes, err := p.admin.PlanTopics(ctx, specs)
+		if err != nil {
+			return nil, err
+		}
+		plan.Topics = changes
+	}
+
+	if len(t.ACLs) > 0 {
+		if err := p.planACLs(ctx, t, plan); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(t.Schemas) > 0 {
+		if p.registry == nil {
+			return nil, fmt.Errorf("topology contains schemas but no schema registry is configured")
+		}
+		if err := p.planSchemas(t, plan); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// planACLs сравнивает ACL топологии с ACL кластера
+func (p *Planner) planACLs(ctx context.Context, t *Topology, plan *Plan) error {
+	desired := make(map[string]kafka.ACLBinding)
+	principals := make(map[string]bool)
+	for _, acl := range t.ACLs {
+		bindings, err := acl.bindings()
+		if err != nil {
+			return err
+		}
+		for _, b := range bindings {
+			desired[admin.FormatACL(b)] = b
+			principals[b.Principal] = true
+		}
+	}
+
+	existing, err := p.admin.ListACLs(ctx)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]bool, len(existing))
+	for _, b := range existing {
+		key := admin.FormatACL(b)
+		current[key] = true
+		if _, ok := desired[key]; !ok && principals[b.Principal] {
+			plan.DeleteACLs = append(plan.DeleteACLs, b)
+		}
+	}
+
+	for key, b := range desired {
+		if !current[key] {
+			plan.CreateACLs = append(plan.CreateACLs, b)
+		}
+	}
+	sort.Slice(plan.CreateACLs, func(i, j int) bool {
+		return admin.FormatACL(plan.CreateACLs[i]) < admin.FormatACL(plan.CreateACLs[j])
+	})
+	return nil
+}
+
+// planSchemas определяет, какие схемы нужно зарегистрировать и чью совместимость изменить.
+// Несовместимая новая версия схемы считается ошибкой плана
+func (p *Planner) planSchemas(t *Topology, plan *Plan) error {
+	for _, s := range t.Schemas {
+		schemaType, err := s.schemaType()
+		if err != nil {
+			return err
+		}
+		change := SchemaChange{Subject: s.Subject, Schema: s.Schema, Type: schemaType}
+
+		_, err = p.registry.LookupSchema(s.Subject, s.Schema, schemaType)
+		switch {
+		case err == nil:
+		case registryErrorCode(err) == registrySubjectNotFound:
+			change.Register = true
+		case registryErrorCode(err) == registrySchemaNotFound:
+			compatible, err := p.registry.IsSchemaCompatible(s.Subject, s.Schema, "latest", schemaType)
+			if err != nil {
+				return fmt.Errorf("failed to check compatibility of %s: %w", s.Subject, err)
+			}
+			if !compatible {
+				return fmt.Errorf("schema for %s is incompatible with the latest version", s.Subject)
+			}
+			change.Register = true
+		default:
+			return fmt.Errorf("failed to look up schema %s: %w", s.Subject, err)
+		}
+
+		if s.Compatibility != "" {
+			level, err := p.registry.GetCompatibilityLevel(s.Subject, false)
+			if err != nil && registryErrorCode(err) != registrySubjectNotFound {
+				return fmt.Errorf("failed to get compatibility of %s: %w", s.Subject, err)
+			}
+			if level != nil {
+				change.CurrentCompatibility = string(*level)
+			}
+			if change.CurrentCompatibility != s.Compatibility {
+				change.Compatibility = s.Compatibility
+			}
+		}
+
+		if change.Register || change.Compatibility != "" {
+			plan.Schemas = append(plan.Schemas, change)
+		}
+	}
+	return nil
+}
+
+// Apply применяет план: топики, затем ACL, затем схемы
+func (p *Planner) Apply(ctx context.Context, plan *Plan) error {
+	if err := p.admin.ApplyTopics(ctx, plan.Topics); err != nil {
+		return err
+	}
+	if err := p.admin.CreateACLs(ctx, plan.CreateACLs); err != nil {
+		return err
+	}
+	if err := p.admin.DeleteACLs(ctx, plan.DeleteACLs); err != nil {
+		return err
+	}
+
+	for _, s := range plan.Schemas {
+		if s.Register {
+			if _, err := p.registry.CreateSchema(s.Subject, s.Schema, s.Type); err != nil {
+				return fmt.Errorf("failed to register schema %s: %w", s.Subject, err)
+			}
+		}
+		if s.Compatibility != "" {
+			level := srclient.CompatibilityLevel(s.Compatibility)
+			if _, err := p.registry.ChangeSubjectCompatibilityLevel(s.Subject, level); err != nil {
+				return fmt.Errorf("failed to change compatibility of %s: %w", s.Subject, err)
+			}
+		}
+	}
+	return nil
+}
+
+// registryErrorCode возвращает код ошибки Schema Registry или 0
+func registryErrorCode(err error) int {
+	var registryErr srclient.Error
+	if errors.As(err, &registryErr) {
+		return registryErr.Code
+	}
+	return 0
+}
@@ -0,0 +1,217 @@
+// Package topology описывает желаемое состояние кластера (топики, ACL, схемы)
+// в YAML-файле и приводит к нему кластер по принципу plan/apply
+package topology
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/kafka-examples/golang/src/admin"
+	"github.com/riferrei/srclient"
+	"gopkg.in/yaml.v3"
+)
+
+// Topology - содержимое файла топологии
+type Topology struct {
+	Topics  []Topic  `yaml:"topics"`
+	ACLs    []ACL    `yaml:"acls"`
+	Schemas []Schema `yaml:"schemas"`
+}
+
+// Topic описывает топик
+type Topic struct {
+	Name              string `yaml:"name"`
+	Partitions        int    `yaml:"partitions"`
+	ReplicationFactor int    `yaml:"replication_factor"`
+	// Retention - длительность в формате Go (168h) или infinite
+	Retention     string            `yaml:"retention"`
+	CleanupPolicy string            `yaml:"cleanup_policy"`
+	Config        map[string]string `yaml:"config"`
+}
+
+// ACL описывает разрешения одного участника на ресурс
+type ACL struct {
+	// Principal - участник в формате User:name
+	Principal string `yaml:"principal"`
+	// Host - адрес клиента; по умолчанию *
+	Host string `yaml:"host"`
+	// ResourceType - topic, group или broker
+	ResourceType string `yaml:"resource_type"`
+	ResourceName string `yaml:"resource_name"`
+	// PatternType - literal (по умолчанию) или prefixed
+	PatternType string `yaml:"pattern_type"`
+	// Operations - read, write, describe, create, delete, alter, all и т.д.
+	Operations []string `yaml:"operations"`
+	// Permission - allow (по умолчанию) или deny
+	Permission string `yaml:"permission"`
+}
+
+// Schema описывает subject в Schema Registry
+type Schema struct {
+	Subject string `yaml:"subject"`
+	// Type - AVRO (по умолчанию), JSON или PROTOBUF
+	Type string `yaml:"type"`
+	// File - путь к файлу схемы относительно файла топологии; альтернатива Schema
+	File   string `yaml:"file"`
+	Schema string `yaml:"schema"`
+	// Compatibility - уровень совместимости subject, например BACKWARD
+	Compatibility string `yaml:"compatibility"`
+}
+
+// Load читает файл топологии; файлы схем загружаются относительно его каталога
+func Load(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topology: %w", err)
+	}
+
+	var t Topology
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to parse topology %s: %w", path, err)
+	}
+
+	for i := range t.Schemas {
+		s := &t.Schemas[i]
+		if s.File == "" {
+			continue
+		}
+		if s.Schema != "" {
+			return nil, fmt.Errorf("schema %s: file and schema are mutually exclusive", s.Subject)
+		}
+		file := s.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %w", s.Subject, err)
+		}
+		s.Schema = string(content)
+	}
+
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// validate проверяет обязательные поля
+func (t *Topology) validate() error {
+	seen := make(map[string]bool)
+	for _, topic := range t.Topics {
+		if topic.Name == "" {
+			return fmt.Errorf("topic without name")
+		}
+		if seen[topic.Name] {
+			return fmt.Errorf("duplicate topic %s", topic.Name)
+		}
+		seen[topic.Name] = true
+		if _, err := topic.spec(); err != nil {
+			return err
+		}
+	}
+	for _, acl := range t.ACLs {
+		if _, err := acl.bindings(); err != nil {
+			return err
+		}
+	}
+	for _, s := range t.Schemas {
+		if s.Subject == "" || s.Schema == "" {
+			return fmt.Errorf("schema requires subject and schema or file")
+		}
+		if _, err := s.schemaType(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spec преобразует описание топика в спецификацию пакета admin
+func (t Topic) spec() (admin.TopicSpec, error) {
+	spec := admin.TopicSpec{
+		Name:              t.Name,
+		Partitions:        t.Partitions,
+		ReplicationFactor: t.ReplicationFactor,
+		CleanupPolicy:     t.CleanupPolicy,
+		Config:            t.Config,
+	}
+
+	switch t.Retention {
+	case "":
+	case "infinite", "-1":
+		spec.Retention = -1
+	default:
+		retention, err := time.ParseDuration(t.Retention)
+		if err != nil {
+			return spec, fmt.Errorf("topic %s: invalid retention %q: %w", t.Name, t.Retention, err)
+		}
+		spec.Retention = retention
+	}
+	return spec, nil
+}
+
+// bindings разворачивает ACL в отдельные привязки для каждой операции
+func (a ACL) bindings() (kafka.ACLBindings, error) {
+	resourceType, err := kafka.ResourceTypeFromString(a.ResourceType)
+	if err != nil {
+		return nil, fmt.Errorf("acl for %s: invalid resource type %q", a.Principal, a.ResourceType)
+	}
+	patternType, err := kafka.ResourcePatternTypeFromString(defaultString(a.PatternType, "literal"))
+	if err != nil {
+		return nil, fmt.Errorf("acl for %s: invalid pattern type %q", a.Principal, a.PatternType)
+	}
+	permission, err := kafka.ACLPermissionTypeFromString(defaultString(a.Permission, "allow"))
+	if err != nil {
+		return nil, fmt.Errorf("acl for %s: invalid permission %q", a.Principal, a.Permission)
+	}
+	if a.Principal == "" || a.ResourceName == "" || len(a.Operations) == 0 {
+		return nil, fmt.Errorf("acl requires principal, resource_name and operations")
+	}
+
+	bindings := make(kafka.ACLBindings, 0, len(a.Operations))
+	for _, op := range a.Operations {
+		operation, err := kafka.ACLOperationFromString(op)
+		if err != nil {
+			return nil, fmt.Errorf("acl for %s: invalid operation %q", a.Principal, op)
+		}
+		bindings = append(bindings, kafka.ACLBinding{
+			Type:                resourceType,
+			Name:                a.ResourceName,
+			ResourcePatternType: patternType,
+			Principal:           a.Principal,
+			Host:                defaultString(a.Host, "*"),
+			Operation:           operation,
+			PermissionType:      permission,
+		})
+	}
+	return bindings, nil
+}
+
+// schemaType возвращает тип схемы srclient
+func (s Schema) schemaType() (srclient.SchemaType, error) {
+	switch strings.ToUpper(defaultString(s.Type, "AVRO")) {
+	case "AVRO":
+		return srclient.Avro, nil
+	case "JSON":
+		return srclient.Json, nil
+	case "PROTOBUF":
+		return srclient.Protobuf, nil
+	default:
+		return "", fmt.Errorf("schema %s: invalid type %q", s.Subject, s.Type)
+	}
+}
+
+// defaultString возвращает value или def, если value пусто
+func defaultString(value string, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}